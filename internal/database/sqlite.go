@@ -0,0 +1,132 @@
+package database
+
+import (
+    "database/sql"
+    "fmt"
+    "log"
+    "net/url"
+    "strings"
+
+    _ "modernc.org/sqlite"
+)
+
+const sqliteMigrationSQL = `
+-- Create entities table
+CREATE TABLE IF NOT EXISTS entities (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE NOT NULL,
+    entity_type TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create observations table
+CREATE TABLE IF NOT EXISTS observations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    entity_id INTEGER REFERENCES entities(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create relations table
+CREATE TABLE IF NOT EXISTS relations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    from_entity_id INTEGER REFERENCES entities(id) ON DELETE CASCADE,
+    to_entity_id INTEGER REFERENCES entities(id) ON DELETE CASCADE,
+    relation_type TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(from_entity_id, to_entity_id, relation_type)
+);
+
+-- Create indexes for performance
+CREATE INDEX IF NOT EXISTS idx_entities_name ON entities(name);
+CREATE INDEX IF NOT EXISTS idx_entities_type ON entities(entity_type);
+CREATE INDEX IF NOT EXISTS idx_observations_entity_id ON observations(entity_id);
+CREATE INDEX IF NOT EXISTS idx_relations_from ON relations(from_entity_id);
+CREATE INDEX IF NOT EXISTS idx_relations_to ON relations(to_entity_id);
+CREATE INDEX IF NOT EXISTS idx_relations_type ON relations(relation_type);
+
+-- Full-text search indexes, kept in sync with the base tables by triggers
+CREATE VIRTUAL TABLE IF NOT EXISTS entities_fts USING fts5(
+    name, content='entities', content_rowid='id', tokenize='porter unicode61'
+);
+CREATE VIRTUAL TABLE IF NOT EXISTS observations_fts USING fts5(
+    content, content='observations', content_rowid='id', tokenize='porter unicode61'
+);
+
+CREATE TRIGGER IF NOT EXISTS entities_fts_insert AFTER INSERT ON entities BEGIN
+    INSERT INTO entities_fts(rowid, name) VALUES (new.id, new.name);
+END;
+CREATE TRIGGER IF NOT EXISTS entities_fts_delete AFTER DELETE ON entities BEGIN
+    INSERT INTO entities_fts(entities_fts, rowid, name) VALUES ('delete', old.id, old.name);
+END;
+CREATE TRIGGER IF NOT EXISTS entities_fts_update AFTER UPDATE OF name ON entities BEGIN
+    INSERT INTO entities_fts(entities_fts, rowid, name) VALUES ('delete', old.id, old.name);
+    INSERT INTO entities_fts(rowid, name) VALUES (new.id, new.name);
+END;
+
+CREATE TRIGGER IF NOT EXISTS observations_fts_insert AFTER INSERT ON observations BEGIN
+    INSERT INTO observations_fts(rowid, content) VALUES (new.id, new.content);
+END;
+CREATE TRIGGER IF NOT EXISTS observations_fts_delete AFTER DELETE ON observations BEGIN
+    INSERT INTO observations_fts(observations_fts, rowid, content) VALUES ('delete', old.id, old.content);
+END;
+CREATE TRIGGER IF NOT EXISTS observations_fts_update AFTER UPDATE OF content ON observations BEGIN
+    INSERT INTO observations_fts(observations_fts, rowid, content) VALUES ('delete', old.id, old.content);
+    INSERT INTO observations_fts(rowid, content) VALUES (new.id, new.content);
+END;
+
+-- Trigger to automatically update updated_at
+CREATE TRIGGER IF NOT EXISTS update_entities_updated_at AFTER UPDATE OF name, entity_type ON entities BEGIN
+    UPDATE entities SET updated_at = CURRENT_TIMESTAMP WHERE id = new.id;
+END;
+`
+
+// SQLitePath extracts the file path from a sqlite://path database URL.
+// It returns false if dbURL is not a SQLite URL.
+func SQLitePath(dbURL string) (string, bool) {
+    if !strings.HasPrefix(dbURL, "sqlite://") {
+        return "", false
+    }
+    return strings.TrimPrefix(dbURL, "sqlite://"), true
+}
+
+func NewSQLiteConnection(path string) (*sql.DB, error) {
+    log.Printf("SQLite database path: %s", path)
+
+    if path == "" {
+        return nil, fmt.Errorf("sqlite database path is empty")
+    }
+
+    // SQLite reads the DSN as a URI, so characters such as ? and # in the
+    // path must be escaped
+    dsn := "file:" + (&url.URL{Path: path}).EscapedPath() +
+        "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+
+    db, err := sql.Open("sqlite", dsn)
+    if err != nil {
+        return nil, fmt.Errorf("failed to open database: %w", err)
+    }
+
+    // SQLite allows a single writer; serialize access through one connection
+    db.SetMaxOpenConns(1)
+
+    if err := db.Ping(); err != nil {
+        db.Close()
+        return nil, fmt.Errorf("failed to ping database: %w", err)
+    }
+
+    log.Println("Database connection established successfully")
+    return db, nil
+}
+
+func RunSQLiteMigrations(db *sql.DB) error {
+    log.Println("Running database migrations...")
+
+    if _, err := db.Exec(sqliteMigrationSQL); err != nil {
+        return err
+    }
+
+    log.Println("Database migrations completed successfully")
+    return nil
+}
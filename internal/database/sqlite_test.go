@@ -0,0 +1,25 @@
+package database
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestNewSQLiteConnectionPath(t *testing.T) {
+    // Characters that have a meaning in a URI must reach the file name
+    path := filepath.Join(t.TempDir(), "memory?mode=ro#1%20.db")
+
+    db, err := NewSQLiteConnection(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer db.Close()
+
+    if err := RunSQLiteMigrations(db); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := os.Stat(path); err != nil {
+        t.Errorf("database was not created at %s: %v", path, err)
+    }
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "mcp-compose-memory/internal/knowledge"
+    "mcp-compose-memory/internal/models"
+)
+
+// maxCompletionValues is the most values completion/complete may return
+const maxCompletionValues = 100
+
+// completionFields maps prompt and resource template argument names to the
+// knowledge graph values that complete them
+var completionFields = map[string]knowledge.CompletionField{
+    "name":         knowledge.CompleteEntityName,
+    "names":        knowledge.CompleteEntityName,
+    "entityName":   knowledge.CompleteEntityName,
+    "from":         knowledge.CompleteEntityName,
+    "to":           knowledge.CompleteEntityName,
+    "entityType":   knowledge.CompleteEntityType,
+    "relationType": knowledge.CompleteRelationType,
+}
+
+func (h *MCPHandler) handleCompletionComplete(ctx context.Context, request *models.MCPRequest) *models.MCPResponse {
+    paramsBytes, _ := json.Marshal(request.Params)
+    var params models.CompletionParams
+    if err := json.Unmarshal(paramsBytes, &params); err != nil {
+        return errorResponse(request.ID, -32602, "Invalid params")
+    }
+
+    // The argument must be one the prompt or template declares
+    var arguments []string
+    switch params.Ref.Type {
+    case "ref/prompt":
+        prompt := h.findPrompt(params.Ref.Name)
+        if prompt == nil {
+            return errorResponse(request.ID, -32602, "Unknown prompt: "+params.Ref.Name)
+        }
+        for _, arg := range prompt.Arguments {
+            arguments = append(arguments, arg.Name)
+        }
+    case "ref/resource":
+        template := findResourceTemplate(params.Ref.URI)
+        if template == nil {
+            return errorResponse(request.ID, -32602, "Unknown resource template: "+params.Ref.URI)
+        }
+        arguments = templateVariables(template.URITemplate)
+    default:
+        return errorResponse(request.ID, -32602, "Invalid params: unknown ref type "+params.Ref.Type)
+    }
+
+    declared := false
+    for _, name := range arguments {
+        declared = declared || name == params.Argument.Name
+    }
+    if !declared {
+        return errorResponse(request.ID, -32602, "Unknown argument: "+params.Argument.Name)
+    }
+
+    completion := models.Completion{Values: []string{}}
+
+    if field, ok := completionFields[params.Argument.Name]; ok {
+        // Ask for one extra value to learn whether there are more
+        values, err := h.manager.Complete(ctx, field, params.Argument.Value, maxCompletionValues+1)
+        if err != nil {
+            return errorResponse(request.ID, -32603, err.Error())
+        }
+
+        if len(values) > maxCompletionValues {
+            values = values[:maxCompletionValues]
+            completion.HasMore = true
+        }
+        completion.Values = append(completion.Values, values...)
+    }
+
+    return &models.MCPResponse{
+        ID:      request.ID,
+        JSONRPC: "2.0",
+        Result:  map[string]interface{}{"completion": completion},
+    }
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+    "testing"
+)
+
+// complete calls completion/complete and returns its completion
+func complete(t *testing.T, response map[string]interface{}) ([]string, bool) {
+    t.Helper()
+
+    completion := result(t, response)["completion"].(map[string]interface{})
+    var values []string
+    for _, value := range completion["values"].([]interface{}) {
+        values = append(values, value.(string))
+    }
+    return values, completion["hasMore"] == true
+}
+
+func TestCompletionComplete(t *testing.T) {
+    // Names complete most recently changed first, so e149 leads
+    h, session := newPagedHandler(t, 150)
+
+    tests := []struct {
+        name        string
+        ref         map[string]interface{}
+        argument    string
+        value       string
+        wantCount   int
+        wantFirst   string
+        wantHasMore bool
+    }{
+        {"prompt argument", map[string]interface{}{"type": "ref/prompt", "name": "recall"}, "name", "e12", 10, "e129", false},
+        {"prompt argument capped", map[string]interface{}{"type": "ref/prompt", "name": "recall"}, "name", "e", 100, "e149", true},
+        {"template variable", map[string]interface{}{"type": "ref/resource", "uri": "memory://type/{entityType}"}, "entityType", "th", 1, "thing", false},
+        {"template variable capped", map[string]interface{}{"type": "ref/resource", "uri": "memory://entity/{name}"}, "name", "", 100, "e149", true},
+        {"no match", map[string]interface{}{"type": "ref/resource", "uri": "memory://entity/{name}"}, "name", "zz", 0, "", false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            values, hasMore := complete(t, call(t, h, session, "completion/complete", map[string]interface{}{
+                "ref":      tt.ref,
+                "argument": map[string]interface{}{"name": tt.argument, "value": tt.value},
+            }))
+            if len(values) != tt.wantCount || hasMore != tt.wantHasMore {
+                t.Fatalf("%d values, hasMore %v; want %d, hasMore %v", len(values), hasMore, tt.wantCount, tt.wantHasMore)
+            }
+            if tt.wantCount > 0 && values[0] != tt.wantFirst {
+                t.Errorf("first value = %s, want %s", values[0], tt.wantFirst)
+            }
+        })
+    }
+}
+
+func TestCompletionCompleteErrors(t *testing.T) {
+    h := newTestHandler()
+    session := newReadySession(h)
+
+    tests := []struct {
+        name     string
+        ref      map[string]interface{}
+        argument string
+    }{
+        {"unknown prompt", map[string]interface{}{"type": "ref/prompt", "name": "no_such_prompt"}, "name"},
+        {"unknown prompt argument", map[string]interface{}{"type": "ref/prompt", "name": "recall"}, "entityType"},
+        {"unknown template", map[string]interface{}{"type": "ref/resource", "uri": "memory://nothing/{name}"}, "name"},
+        {"unknown template variable", map[string]interface{}{"type": "ref/resource", "uri": "memory://type/{entityType}"}, "name"},
+        {"unknown ref type", map[string]interface{}{"type": "ref/tool", "name": "read_graph"}, "name"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            response := call(t, h, session, "completion/complete", map[string]interface{}{
+                "ref":      tt.ref,
+                "argument": map[string]interface{}{"name": tt.argument, "value": ""},
+            })
+            if code := errorCode(response); code != -32602 {
+                t.Errorf("error code = %d, want -32602 (%v)", code, response["error"])
+            }
+        })
+    }
+}
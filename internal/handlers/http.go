@@ -0,0 +1,272 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Streamable HTTP transport (MCP revision 2025-03-26).
+//
+// POST delivers client messages and answers with either a JSON body or an
+// SSE stream, GET opens the standalone server-to-client SSE stream, and
+// DELETE ends the session named by the Mcp-Session-Id header.
+
+const (
+    sessionHeader     = "Mcp-Session-Id"
+    lastEventIDHeader = "Last-Event-ID"
+
+    // sseKeepAlive is how often an idle SSE stream receives a comment so
+    // that proxies do not close it
+    sseKeepAlive = 30 * time.Second
+
+    // responseWriteMargin is how long a response may take to write after
+    // the tool calls it answers have run out of time
+    responseWriteMargin = 30 * time.Second
+)
+
+// acceptsEventStream reports whether the client can receive an SSE response
+func acceptsEventStream(r *http.Request) bool {
+    for _, accept := range r.Header.Values("Accept") {
+        for _, mediaType := range strings.Split(accept, ",") {
+            mediaType = strings.TrimSpace(strings.SplitN(mediaType, ";", 2)[0])
+            if mediaType == "text/event-stream" {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// lookupSession resolves the session for a request, writing the error
+// response required by the transport when it is missing or unknown
+func (h *MCPHandler) lookupSession(w http.ResponseWriter, r *http.Request) *Session {
+    id := r.Header.Get(sessionHeader)
+    if id == "" {
+        http.Error(w, "Missing "+sessionHeader+" header", http.StatusBadRequest)
+        return nil
+    }
+
+    session := h.sessions.get(id)
+    if session == nil {
+        http.Error(w, "Session not found", http.StatusNotFound)
+        return nil
+    }
+    return session
+}
+
+// HandleMCPRequest handles POSTed JSON-RPC messages
+func (h *MCPHandler) HandleMCPRequest(w http.ResponseWriter, r *http.Request) {
+    body, err := io.ReadAll(r.Body)
+    if err != nil {
+        h.sendResponse(w, errorResponse(nil, -32700, "Parse error"))
+        return
+    }
+
+    messages, batch, errResponse := parseMessages(body)
+    if errResponse != nil {
+        h.sendResponse(w, errResponse)
+        return
+    }
+
+    // A lone invalid message fails before it can need a session
+    if !batch && messages[0].invalid != nil {
+        h.sendResponse(w, messages[0].invalid)
+        return
+    }
+
+    // initialize starts a new session; everything else must name one
+    var session *Session
+    if !batch && messages[0].request.Method == "initialize" {
+        session = h.sessions.create()
+        w.Header().Set(sessionHeader, session.ID)
+        log.Printf("Created session %s", session.ID)
+    } else if session = h.lookupSession(w, r); session == nil {
+        return
+    }
+
+    // A tool allowed to run longer than the server's WriteTimeout must
+    // still be able to deliver its result, or its timeout error
+    if timeout := h.tools.callsTimeout(messages); timeout > 0 {
+        http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + responseWriteMargin))
+    }
+
+    // Clients that accept SSE also receive the notifications about their
+    // requests, such as progress, ahead of the response
+    ctx := r.Context()
+    var stream *sseResponse
+    if acceptsEventStream(r) {
+        stream = &sseResponse{w: w}
+        ctx = withRequestStream(ctx, stream.send)
+    }
+
+    reply := h.handleMessages(ctx, session, messages, batch)
+    if reply == nil {
+        if stream == nil || !stream.started {
+            w.WriteHeader(http.StatusAccepted)
+        }
+        return
+    }
+
+    if stream != nil {
+        data, err := json.Marshal(reply)
+        if err != nil {
+            log.Printf("Failed to encode response: %v", err)
+            return
+        }
+        stream.send(data)
+        return
+    }
+    h.sendResponse(w, reply)
+}
+
+// HandleMCPStream opens the standalone SSE stream on which the server sends
+// requests and notifications that are not tied to a client request
+func (h *MCPHandler) HandleMCPStream(w http.ResponseWriter, r *http.Request) {
+    if !acceptsEventStream(r) {
+        http.Error(w, "Client must accept text/event-stream", http.StatusNotAcceptable)
+        return
+    }
+
+    session := h.lookupSession(w, r)
+    if session == nil {
+        return
+    }
+
+    stream, replay := session.attachStream(r.Header.Get(lastEventIDHeader))
+    defer session.detachStream(stream)
+
+    // The stream outlives the server's WriteTimeout
+    http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("Cache-Control", "no-cache")
+    w.WriteHeader(http.StatusOK)
+    flush(w)
+
+    for _, event := range replay {
+        if err := writeSSE(w, event.ID, event.Data); err != nil {
+            return
+        }
+    }
+
+    keepAlive := time.NewTicker(sseKeepAlive)
+    defer keepAlive.Stop()
+
+    for {
+        select {
+        case event, ok := <-stream:
+            if !ok {
+                // Replaced by a newer stream for the same session
+                return
+            }
+            if err := writeSSE(w, event.ID, event.Data); err != nil {
+                return
+            }
+        case <-keepAlive.C:
+            session.touch()
+            if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+                return
+            }
+            flush(w)
+        case <-session.closed:
+            return
+        case <-r.Context().Done():
+            return
+        }
+    }
+}
+
+// HandleMCPDelete terminates a session at the client's request
+func (h *MCPHandler) HandleMCPDelete(w http.ResponseWriter, r *http.Request) {
+    id := r.Header.Get(sessionHeader)
+    if id == "" {
+        http.Error(w, "Missing "+sessionHeader+" header", http.StatusBadRequest)
+        return
+    }
+
+    if !h.sessions.remove(id) {
+        http.Error(w, "Session not found", http.StatusNotFound)
+        return
+    }
+
+    log.Printf("Terminated session %s", id)
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// ExpireSessions discards the HTTP sessions left idle for longer than
+// sessionIdleTimeout until ctx is done
+func (h *MCPHandler) ExpireSessions(ctx context.Context) {
+    ticker := time.NewTicker(sessionSweepInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case now := <-ticker.C:
+            if n := h.sessions.expire(now.Add(-sessionIdleTimeout)); n > 0 {
+                log.Printf("Expired %d idle sessions", n)
+            }
+        case <-ctx.Done():
+            return
+        }
+    }
+}
+
+// CloseSessions ends every HTTP session, closing their open SSE streams so
+// that a graceful shutdown does not wait on them
+func (h *MCPHandler) CloseSessions() {
+    h.sessions.closeAll()
+}
+
+func (h *MCPHandler) sendResponse(w http.ResponseWriter, response interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusOK) // JSON-RPC errors use 200 status
+    json.NewEncoder(w).Encode(response)
+}
+
+// sseResponse answers a POST with an SSE stream, which starts with the
+// first message sent on it and ends with the response
+type sseResponse struct {
+    w       http.ResponseWriter
+    mu      sync.Mutex
+    started bool
+}
+
+func (s *sseResponse) send(message []byte) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if !s.started {
+        s.w.Header().Set("Content-Type", "text/event-stream")
+        s.w.Header().Set("Cache-Control", "no-cache")
+        s.w.WriteHeader(http.StatusOK)
+        s.started = true
+    }
+    return writeSSE(s.w, "", message)
+}
+
+func writeSSE(w http.ResponseWriter, id string, data []byte) error {
+    var event strings.Builder
+    if id != "" {
+        fmt.Fprintf(&event, "id: %s\n", id)
+    }
+    fmt.Fprintf(&event, "event: message\ndata: %s\n\n", data)
+
+    if _, err := io.WriteString(w, event.String()); err != nil {
+        return err
+    }
+    flush(w)
+    return nil
+}
+
+func flush(w http.ResponseWriter) {
+    if f, ok := w.(http.Flusher); ok {
+        f.Flush()
+    }
+}
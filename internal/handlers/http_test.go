@@ -0,0 +1,261 @@
+package handlers
+
+import (
+    "bufio"
+    "encoding/json"
+    "mcp-compose-memory/internal/knowledge"
+    "mcp-compose-memory/internal/models"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+func newTestHandler() *MCPHandler {
+    return NewMCPHandler(knowledge.NewManager(knowledge.NewMemoryStore()))
+}
+
+func TestHandleMCPRequestInvalidMessage(t *testing.T) {
+    tests := []struct {
+        name string
+        body string
+    }{
+        {"id without method", `{"jsonrpc":"2.0","id":1}`},
+        {"empty object", `{}`},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            h := newTestHandler()
+            req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+            rec := httptest.NewRecorder()
+
+            h.HandleMCPRequest(rec, req)
+
+            if rec.Code != http.StatusOK {
+                t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+            }
+            var response models.MCPResponse
+            if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+                t.Fatalf("decoding response: %v", err)
+            }
+            if response.Error == nil || response.Error.Code != -32600 {
+                t.Fatalf("error = %+v, want code -32600", response.Error)
+            }
+            if rec.Header().Get(sessionHeader) != "" {
+                t.Errorf("invalid message created a session")
+            }
+        })
+    }
+}
+
+// newTestServer serves h over HTTP the way main routes it
+func newTestServer(h *MCPHandler) *httptest.Server {
+    return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        switch r.Method {
+        case http.MethodPost:
+            h.HandleMCPRequest(w, r)
+        case http.MethodGet:
+            h.HandleMCPStream(w, r)
+        case http.MethodDelete:
+            h.HandleMCPDelete(w, r)
+        }
+    }))
+}
+
+// send makes an HTTP request to the server at url for the session id and
+// returns the response, whose body the caller must close
+func send(t *testing.T, method, url, id, body string, header ...string) *http.Response {
+    t.Helper()
+
+    req, err := http.NewRequest(method, url, strings.NewReader(body))
+    if err != nil {
+        t.Fatal(err)
+    }
+    req.Header.Set("Content-Type", "application/json")
+    if id != "" {
+        req.Header.Set(sessionHeader, id)
+    }
+    for i := 0; i+1 < len(header); i += 2 {
+        req.Header.Set(header[i], header[i+1])
+    }
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return resp
+}
+
+// openSession initializes a session on the server at url and returns its id
+func openSession(t *testing.T, url string) string {
+    t.Helper()
+
+    resp := send(t, http.MethodPost, url, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`)
+    resp.Body.Close()
+    id := resp.Header.Get(sessionHeader)
+    if id == "" {
+        t.Fatal("initialize returned no session id")
+    }
+
+    resp = send(t, http.MethodPost, url, id, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusAccepted {
+        t.Fatalf("notifications/initialized: status %d, want %d", resp.StatusCode, http.StatusAccepted)
+    }
+    return id
+}
+
+func TestSessionLifecycle(t *testing.T) {
+    server := newTestServer(newTestHandler())
+    defer server.Close()
+
+    ping := `{"jsonrpc":"2.0","id":2,"method":"ping"}`
+    status := func(method, id, body string) int {
+        resp := send(t, method, server.URL, id, body)
+        resp.Body.Close()
+        return resp.StatusCode
+    }
+
+    if code := status(http.MethodPost, "unknown", ping); code != http.StatusNotFound {
+        t.Errorf("unknown session: status %d, want %d", code, http.StatusNotFound)
+    }
+    if code := status(http.MethodPost, "", ping); code != http.StatusBadRequest {
+        t.Errorf("no session: status %d, want %d", code, http.StatusBadRequest)
+    }
+
+    id := openSession(t, server.URL)
+    if code := status(http.MethodPost, id, ping); code != http.StatusOK {
+        t.Errorf("open session: status %d, want %d", code, http.StatusOK)
+    }
+    if code := status(http.MethodDelete, id, ""); code != http.StatusNoContent {
+        t.Errorf("DELETE: status %d, want %d", code, http.StatusNoContent)
+    }
+    if code := status(http.MethodPost, id, ping); code != http.StatusNotFound {
+        t.Errorf("deleted session: status %d, want %d", code, http.StatusNotFound)
+    }
+    if code := status(http.MethodDelete, id, ""); code != http.StatusNotFound {
+        t.Errorf("second DELETE: status %d, want %d", code, http.StatusNotFound)
+    }
+}
+
+func TestSessionExpiry(t *testing.T) {
+    h := newTestHandler()
+    idle := h.sessions.create()
+    active := h.sessions.create()
+    idle.mu.Lock()
+    idle.lastSeen = time.Now().Add(-2 * sessionIdleTimeout)
+    idle.mu.Unlock()
+
+    if n := h.sessions.expire(time.Now().Add(-sessionIdleTimeout)); n != 1 {
+        t.Errorf("expired %d sessions, want 1", n)
+    }
+    if h.sessions.get(idle.ID) != nil {
+        t.Error("idle session was kept")
+    }
+    if h.sessions.get(active.ID) == nil {
+        t.Error("active session was expired")
+    }
+    select {
+    case <-idle.closed:
+    default:
+        t.Error("expired session was not closed")
+    }
+}
+
+// sseReader reads the events of an SSE stream
+type sseReader struct {
+    t      *testing.T
+    events chan [2]string
+}
+
+func newSSEReader(t *testing.T, resp *http.Response) *sseReader {
+    r := &sseReader{t: t, events: make(chan [2]string, 16)}
+    go func() {
+        defer close(r.events)
+        scanner := bufio.NewScanner(resp.Body)
+        var id string
+        for scanner.Scan() {
+            line := scanner.Text()
+            switch {
+            case strings.HasPrefix(line, "id: "):
+                id = strings.TrimPrefix(line, "id: ")
+            case strings.HasPrefix(line, "data: "):
+                r.events <- [2]string{id, strings.TrimPrefix(line, "data: ")}
+                id = ""
+            }
+        }
+    }()
+    return r
+}
+
+// next returns the id and data of the next event
+func (r *sseReader) next() (string, string) {
+    r.t.Helper()
+
+    select {
+    case event, ok := <-r.events:
+        if !ok {
+            r.t.Fatal("stream ended")
+        }
+        return event[0], event[1]
+    case <-time.After(5 * time.Second):
+        r.t.Fatal("no event on the stream")
+    }
+    return "", ""
+}
+
+func TestMCPStream(t *testing.T) {
+    h := newTestHandler()
+    server := newTestServer(h)
+    defer server.Close()
+    id := openSession(t, server.URL)
+
+    resp := send(t, http.MethodGet, server.URL, id, "")
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusNotAcceptable {
+        t.Errorf("GET without Accept: status %d, want %d", resp.StatusCode, http.StatusNotAcceptable)
+    }
+
+    resp = send(t, http.MethodGet, server.URL, id, "", "Accept", "text/event-stream")
+    if resp.StatusCode != http.StatusOK {
+        t.Fatalf("GET: status %d, want %d", resp.StatusCode, http.StatusOK)
+    }
+    stream := newSSEReader(t, resp)
+
+    createEntity := func(name string) {
+        resp := send(t, http.MethodPost, server.URL, id, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"create_entities","arguments":{"entities":[{"name":"`+name+`","entityType":"person","observations":[]}]}}}`)
+        resp.Body.Close()
+    }
+
+    // Notifications not tied to a request arrive on the stream
+    createEntity("alice")
+    first, data := stream.next()
+    if first == "" || !strings.Contains(data, "notifications/resources/list_changed") {
+        t.Fatalf("event %q: %s, want list_changed with an id", first, data)
+    }
+
+    // Events sent while the client is away are replayed after the last one
+    // it saw when it reconnects
+    resp.Body.Close()
+    createEntity("bob")
+    session := h.sessions.get(id)
+    deadline := time.Now().Add(5 * time.Second)
+    for {
+        session.mu.Lock()
+        sent := len(session.history)
+        session.mu.Unlock()
+        if sent == 2 || time.Now().After(deadline) {
+            break
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+
+    resp = send(t, http.MethodGet, server.URL, id, "", "Accept", "text/event-stream", lastEventIDHeader, first)
+    defer resp.Body.Close()
+    replayed, data := newSSEReader(t, resp).next()
+    if replayed == first || !strings.Contains(data, "notifications/resources/list_changed") {
+        t.Errorf("replayed event %q: %s, want the list_changed after %q", replayed, data, first)
+    }
+}
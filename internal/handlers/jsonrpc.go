@@ -0,0 +1,114 @@
+package handlers
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "log"
+    "mcp-compose-memory/internal/models"
+)
+
+// incomingMessage is one decoded element of a JSON-RPC message or batch
+type incomingMessage struct {
+    request *models.MCPRequest
+
+    // notification is set when the message has no id and so expects no response
+    notification bool
+
+    // response is set when the message is the client's reply to a server request
+    response bool
+
+    // invalid holds the error response for an element that is not a valid request
+    invalid *models.MCPResponse
+}
+
+// parseMessages decodes a single JSON-RPC message or a batch of them. A
+// non-nil error response means the body as a whole could not be used.
+func parseMessages(body []byte) ([]incomingMessage, bool, *models.MCPResponse) {
+    trimmed := bytes.TrimSpace(body)
+
+    if len(trimmed) == 0 || trimmed[0] != '[' {
+        message, err := parseMessage(trimmed)
+        if err != nil {
+            return nil, false, err
+        }
+        return []incomingMessage{message}, false, nil
+    }
+
+    var elements []json.RawMessage
+    if err := json.Unmarshal(trimmed, &elements); err != nil {
+        return nil, true, errorResponse(nil, -32700, "Parse error")
+    }
+    if len(elements) == 0 {
+        return nil, true, errorResponse(nil, -32600, "Invalid Request: empty batch")
+    }
+
+    messages := make([]incomingMessage, 0, len(elements))
+    for _, element := range elements {
+        message, err := parseMessage(element)
+        if err != nil {
+            // Within a batch a malformed element only fails itself
+            message = incomingMessage{invalid: errorResponse(nil, -32600, "Invalid Request")}
+        }
+        messages = append(messages, message)
+    }
+    return messages, true, nil
+}
+
+func parseMessage(data []byte) (incomingMessage, *models.MCPResponse) {
+    var request models.MCPRequest
+    if err := json.Unmarshal(data, &request); err != nil {
+        return incomingMessage{}, errorResponse(nil, -32700, "Parse error")
+    }
+
+    // A request is a notification when the id member is absent, which
+    // cannot be told apart from "id": null once decoded into MCPRequest,
+    // nor once decoded into a pointer, so look for the member itself
+    var envelope map[string]json.RawMessage
+    json.Unmarshal(data, &envelope)
+    _, hasID := envelope["id"]
+    _, hasResult := envelope["result"]
+    _, hasError := envelope["error"]
+
+    if request.Method == "" && (hasResult || hasError) {
+        return incomingMessage{request: &request, response: true}, nil
+    }
+    if request.Method == "" {
+        return incomingMessage{invalid: errorResponse(request.ID, -32600, "Invalid Request")}, nil
+    }
+
+    return incomingMessage{request: &request, notification: !hasID}, nil
+}
+
+// handleMessages dispatches decoded messages in order. It returns a single
+// response, a slice of responses for a batch, or nil when there is nothing
+// to send back because every message was a notification, a response, or a
+// cancelled request.
+func (h *MCPHandler) handleMessages(ctx context.Context, session *Session, messages []incomingMessage, batch bool) interface{} {
+    var responses []*models.MCPResponse
+    for _, message := range messages {
+        switch {
+        case message.invalid != nil:
+            responses = append(responses, message.invalid)
+        case message.response:
+            // The server sends no requests that await a reply
+            log.Printf("Ignoring response to unknown request %v", message.request.ID)
+        case message.notification:
+            h.handleNotification(session, message.request)
+        case batch && message.request.Method == "initialize":
+            responses = append(responses, errorResponse(message.request.ID, -32600, "Invalid Request: initialize must not be part of a batch"))
+        default:
+            if response := h.handleRequest(ctx, session, message.request); response != nil {
+                responses = append(responses, response)
+            }
+        }
+    }
+
+    if len(responses) == 0 {
+        return nil
+    }
+    if !batch {
+        return responses[0]
+    }
+    return responses
+}
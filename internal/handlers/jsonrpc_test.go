@@ -0,0 +1,152 @@
+package handlers
+
+import (
+    "context"
+    "mcp-compose-memory/internal/models"
+    "testing"
+)
+
+// messageKind summarizes how parseMessage classified a message
+func messageKind(message incomingMessage) string {
+    switch {
+    case message.invalid != nil:
+        return "invalid"
+    case message.response:
+        return "response"
+    case message.notification:
+        return "notification"
+    default:
+        return "request"
+    }
+}
+
+func TestParseMessages(t *testing.T) {
+    tests := []struct {
+        name      string
+        body      string
+        wantBatch bool
+        wantKinds []string
+        wantError int
+    }{
+        {"request", `{"jsonrpc":"2.0","id":1,"method":"ping"}`, false, []string{"request"}, 0},
+        {"null id is a request", `{"jsonrpc":"2.0","id":null,"method":"ping"}`, false, []string{"request"}, 0},
+        {"notification", `{"jsonrpc":"2.0","method":"notifications/initialized"}`, false, []string{"notification"}, 0},
+        {"response", `{"jsonrpc":"2.0","id":1,"result":{}}`, false, []string{"response"}, 0},
+        {"error response", `{"jsonrpc":"2.0","id":1,"error":{"code":1,"message":"no"}}`, false, []string{"response"}, 0},
+        {"missing method", `{"jsonrpc":"2.0","id":1}`, false, []string{"invalid"}, 0},
+        {"malformed", `{"jsonrpc":`, false, nil, -32700},
+        {"empty body", ``, false, nil, -32700},
+        {"batch", ` [{"jsonrpc":"2.0","id":1,"method":"ping"},{"jsonrpc":"2.0","method":"notifications/initialized"}]`, true, []string{"request", "notification"}, 0},
+        {"batch with invalid elements", `[1,{"jsonrpc":"2.0","id":2},{"jsonrpc":"2.0","id":3,"method":"ping"}]`, true, []string{"invalid", "invalid", "request"}, 0},
+        {"empty batch", `[]`, true, nil, -32600},
+        {"malformed batch", `[{"jsonrpc":"2.0"},`, true, nil, -32700},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            messages, batch, errResponse := parseMessages([]byte(tt.body))
+            if tt.wantError != 0 {
+                if errResponse == nil || errResponse.Error.Code != tt.wantError {
+                    t.Fatalf("error = %+v, want code %d", errResponse, tt.wantError)
+                }
+                return
+            }
+            if errResponse != nil {
+                t.Fatalf("unexpected error %+v", errResponse.Error)
+            }
+
+            if batch != tt.wantBatch {
+                t.Errorf("batch = %v, want %v", batch, tt.wantBatch)
+            }
+            if len(messages) != len(tt.wantKinds) {
+                t.Fatalf("got %d messages, want %d", len(messages), len(tt.wantKinds))
+            }
+            for i, message := range messages {
+                if kind := messageKind(message); kind != tt.wantKinds[i] {
+                    t.Errorf("message %d is a %s, want a %s", i, kind, tt.wantKinds[i])
+                }
+            }
+        })
+    }
+}
+
+func TestHandleMessages(t *testing.T) {
+    tests := []struct {
+        name  string
+        body  string
+        ready bool
+        // want lists the error code of each response, or 0 for a result; nil
+        // means no reply at all
+        want []int
+    }{
+        {"request", `{"jsonrpc":"2.0","id":1,"method":"ping"}`, true, []int{0}},
+        {"notification", `{"jsonrpc":"2.0","method":"notifications/initialized"}`, true, nil},
+        {"response", `{"jsonrpc":"2.0","id":1,"result":{}}`, true, nil},
+        {"unknown method", `{"jsonrpc":"2.0","id":1,"method":"nope"}`, true, []int{-32601}},
+        {"before initialize", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, false, []int{-32002}},
+        {"ping before initialize", `{"jsonrpc":"2.0","id":1,"method":"ping"}`, false, []int{0}},
+        {"batch", `[
+            {"jsonrpc":"2.0","id":1,"method":"ping"},
+            {"jsonrpc":"2.0","method":"notifications/initialized"},
+            {"jsonrpc":"2.0","id":2,"method":"tools/list"}
+        ]`, true, []int{0, 0}},
+        {"batch of notifications", `[
+            {"jsonrpc":"2.0","method":"notifications/initialized"},
+            {"jsonrpc":"2.0","method":"notifications/unknown"}
+        ]`, true, nil},
+        {"batch with invalid elements", `[1,{"jsonrpc":"2.0","id":2,"method":"ping"},{"jsonrpc":"2.0","id":3}]`, true, []int{-32600, 0, -32600}},
+        {"initialize in a batch", `[{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}]`, false, []int{-32600}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            h := newTestHandler()
+            session := newSession(newSessionID())
+            if tt.ready {
+                session.setState(stateReady)
+            }
+
+            messages, batch, errResponse := parseMessages([]byte(tt.body))
+            if errResponse != nil {
+                t.Fatalf("parseMessages: %+v", errResponse.Error)
+            }
+            reply := h.handleMessages(context.Background(), session, messages, batch)
+
+            var responses []*models.MCPResponse
+            switch r := reply.(type) {
+            case nil:
+            case *models.MCPResponse:
+                if batch {
+                    t.Fatalf("batch answered with a single response")
+                }
+                responses = []*models.MCPResponse{r}
+            case []*models.MCPResponse:
+                if !batch {
+                    t.Fatalf("single message answered with a batch")
+                }
+                responses = r
+            default:
+                t.Fatalf("unexpected reply %T", reply)
+            }
+
+            if tt.want == nil {
+                if responses != nil {
+                    t.Fatalf("got %d responses, want no reply", len(responses))
+                }
+                return
+            }
+            if len(responses) != len(tt.want) {
+                t.Fatalf("got %d responses, want %d", len(responses), len(tt.want))
+            }
+            for i, response := range responses {
+                code := 0
+                if response.Error != nil {
+                    code = response.Error.Code
+                }
+                if code != tt.want[i] {
+                    t.Errorf("response %d has code %d, want %d", i, code, tt.want[i])
+                }
+            }
+        })
+    }
+}
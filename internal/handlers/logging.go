@@ -0,0 +1,66 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "mcp-compose-memory/internal/knowledge"
+    "mcp-compose-memory/internal/models"
+)
+
+// defaultLogLevel is the least severe level sent to a session that has not
+// chosen one with logging/setLevel, so that clients see skipped writes
+// without asking
+const defaultLogLevel = knowledge.LevelWarning
+
+// logLevelSeverity orders the MCP logging levels from least to most severe
+var logLevelSeverity = map[knowledge.LogLevel]int{
+    knowledge.LevelDebug:     0,
+    knowledge.LevelInfo:      1,
+    knowledge.LevelNotice:    2,
+    knowledge.LevelWarning:   3,
+    knowledge.LevelError:     4,
+    knowledge.LevelCritical:  5,
+    knowledge.LevelAlert:     6,
+    knowledge.LevelEmergency: 7,
+}
+
+// sessionLogger sends the log records of the request ctx belongs to as
+// notifications/message, if they are at or above the session's log level
+func sessionLogger(ctx context.Context, session *Session) knowledge.LogFunc {
+    return func(record knowledge.LogRecord) {
+        if logLevelSeverity[record.Level] < logLevelSeverity[session.LogLevel()] {
+            return
+        }
+
+        data := map[string]interface{}{"message": record.Message}
+        for key, value := range record.Data {
+            data[key] = value
+        }
+        session.notifyRequest(ctx, "notifications/message", map[string]interface{}{
+            "level":  record.Level,
+            "logger": record.Logger,
+            "data":   data,
+        })
+    }
+}
+
+func (h *MCPHandler) handleLoggingSetLevel(session *Session, request *models.MCPRequest) *models.MCPResponse {
+    paramsBytes, _ := json.Marshal(request.Params)
+    var params struct {
+        Level knowledge.LogLevel `json:"level"`
+    }
+    if err := json.Unmarshal(paramsBytes, &params); err != nil {
+        return errorResponse(request.ID, -32602, "Invalid params")
+    }
+    if _, ok := logLevelSeverity[params.Level]; !ok {
+        return errorResponse(request.ID, -32602, "Invalid params: unknown log level "+string(params.Level))
+    }
+
+    session.setLogLevel(params.Level)
+
+    return &models.MCPResponse{
+        ID:      request.ID,
+        JSONRPC: "2.0",
+        Result:  map[string]interface{}{},
+    }
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+    "reflect"
+    "testing"
+    "time"
+)
+
+// logMessages collects the params of the notifications/message that arrive
+// on messages until none has arrived for a while
+func logMessages(messages chan map[string]interface{}) []map[string]interface{} {
+    var got []map[string]interface{}
+    for {
+        select {
+        case message := <-messages:
+            if message["method"] == "notifications/message" {
+                got = append(got, message["params"].(map[string]interface{}))
+            }
+        case <-time.After(100 * time.Millisecond):
+            return got
+        }
+    }
+}
+
+func TestLoggingSetLevel(t *testing.T) {
+    h := newTestHandler()
+    session := newReadySession(h)
+
+    for _, level := range []string{"debug", "warning", "emergency"} {
+        if code := errorCode(call(t, h, session, "logging/setLevel", map[string]interface{}{"level": level})); code != 0 {
+            t.Errorf("setLevel %s: error code %d", level, code)
+        }
+    }
+    for _, level := range []string{"verbose", ""} {
+        if code := errorCode(call(t, h, session, "logging/setLevel", map[string]interface{}{"level": level})); code != -32602 {
+            t.Errorf("setLevel %q: error code %d, want -32602", level, code)
+        }
+    }
+}
+
+func TestSkippedRelationLogged(t *testing.T) {
+    h := newTestHandler()
+    session, messages := newWriterSession(h)
+    defer h.sessions.remove(session.ID)
+    createEntity(t, h, session, "alice")
+    logMessages(messages)
+
+    createRelation := func(to string) {
+        t.Helper()
+        call(t, h, session, "tools/call", map[string]interface{}{
+            "name": "create_relations",
+            "arguments": map[string]interface{}{"relations": []interface{}{
+                map[string]interface{}{"from": "alice", "to": to, "relationType": "knows"},
+            }},
+        })
+    }
+
+    // Warnings reach a session that has not set a level
+    createRelation("nobody")
+    got := logMessages(messages)
+    if len(got) != 1 {
+        t.Fatalf("got %d log messages, want 1: %v", len(got), got)
+    }
+    if got[0]["level"] != "warning" || got[0]["logger"] != "knowledge" {
+        t.Errorf("level %v, logger %v; want warning, knowledge", got[0]["level"], got[0]["logger"])
+    }
+    data := got[0]["data"].(map[string]interface{})
+    if data["from"] != "alice" || data["to"] != "nobody" || !reflect.DeepEqual(data["missingEntities"], []interface{}{"nobody"}) {
+        t.Errorf("data = %v, want the skipped relation and its missing entity", data)
+    }
+
+    // Above the session's level they are not sent
+    call(t, h, session, "logging/setLevel", map[string]interface{}{"level": "error"})
+    createRelation("someone")
+    if got := logMessages(messages); len(got) != 0 {
+        t.Errorf("at level error got %v, want no log messages", got)
+    }
+}
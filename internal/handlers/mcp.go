@@ -1,59 +1,126 @@
 package handlers
 
 import (
+    "context"
     "encoding/json"
-    "io"
+    "errors"
     "log"
     "mcp-compose-memory/internal/knowledge"
     "mcp-compose-memory/internal/models"
-    "net/http"
+    "mcp-compose-memory/internal/prompts"
 )
 
 type MCPHandler struct {
-    manager *knowledge.Manager
+    manager  *knowledge.Manager
+    sessions *sessionStore
+    tools    *toolRegistry
+    prompts  []*prompts.Prompt
 }
 
 func NewMCPHandler(manager *knowledge.Manager) *MCPHandler {
-    return &MCPHandler{manager: manager}
+    h := &MCPHandler{
+        manager:  manager,
+        sessions: newSessionStore(),
+        tools:    newToolRegistry(),
+    }
+    h.registerTools()
+    h.AddPrompts(prompts.Builtin()...)
+    manager.OnChange(h.handleGraphChange)
+    return h
 }
 
-func (h *MCPHandler) HandleMCPRequest(w http.ResponseWriter, r *http.Request) {
-    w.Header().Set("Content-Type", "application/json")
-
-    body, err := io.ReadAll(r.Body)
-    if err != nil {
-        h.sendError(w, nil, -32700, "Parse error")
-        return
+// HandleMessage decodes a JSON-RPC message or batch and dispatches it,
+// independent of the transport it arrived on. It returns the value to
+// encode as the reply, or nil if no reply is due. Requests run under ctx.
+func (h *MCPHandler) HandleMessage(ctx context.Context, session *Session, body []byte) interface{} {
+    messages, batch, errResponse := parseMessages(body)
+    if errResponse != nil {
+        return errResponse
     }
 
-    log.Printf("Received HTTP request: %s", string(body))
+    return h.handleMessages(ctx, session, messages, batch)
+}
+
+func (h *MCPHandler) dispatch(ctx context.Context, session *Session, request *models.MCPRequest) *models.MCPResponse {
+    switch request.Method {
+    case "initialize":
+        return h.handleInitialize(session, request)
+    case "ping":
+        return &models.MCPResponse{
+            ID:      request.ID,
+            JSONRPC: "2.0",
+            Result:  map[string]interface{}{},
+        }
+    }
 
-    var request models.MCPRequest
-    if err := json.Unmarshal(body, &request); err != nil {
-        h.sendError(w, nil, -32700, "Parse error")
-        return
+    if session.State() == stateNew {
+        return errorResponse(request.ID, -32002, "Server not initialized")
     }
 
     switch request.Method {
-    case "initialize":
-        h.handleInitialize(w, &request)
     case "tools/list":
-        h.handleToolsList(w, &request)
+        return h.handleToolsList(request)
     case "tools/call":
-        h.handleToolsCall(w, &request)
+        return h.handleToolsCall(ctx, request)
+    case "resources/list":
+        return h.handleResourcesList(ctx, request)
+    case "resources/templates/list":
+        return h.handleResourceTemplatesList(request)
+    case "resources/read":
+        return h.handleResourcesRead(ctx, request)
+    case "prompts/list":
+        return h.handlePromptsList(request)
+    case "prompts/get":
+        return h.handlePromptsGet(ctx, request)
+    case "completion/complete":
+        return h.handleCompletionComplete(ctx, request)
+    case "resources/subscribe":
+        return h.handleResourcesSubscribe(session, request)
+    case "resources/unsubscribe":
+        return h.handleResourcesUnsubscribe(session, request)
+    case "logging/setLevel":
+        return h.handleLoggingSetLevel(session, request)
     default:
-        h.sendError(w, request.ID, -32601, "Method not found")
+        return errorResponse(request.ID, -32601, "Method not found")
     }
 }
 
-func (h *MCPHandler) handleInitialize(w http.ResponseWriter, request *models.MCPRequest) {
-    response := models.MCPResponse{
+// handleNotification processes a message that expects no response
+func (h *MCPHandler) handleNotification(session *Session, notification *models.MCPRequest) {
+    switch notification.Method {
+    case "notifications/initialized":
+        if session.State() == stateNew {
+            log.Printf("Session %s sent initialized before initialize", session.ID)
+            return
+        }
+        session.setState(stateReady)
+    case "notifications/cancelled":
+        h.handleCancelled(session, notification)
+    default:
+        log.Printf("Ignoring unknown notification %s", notification.Method)
+    }
+}
+
+func (h *MCPHandler) handleInitialize(session *Session, request *models.MCPRequest) *models.MCPResponse {
+    if session.State() != stateNew {
+        return errorResponse(request.ID, -32600, "Invalid Request: session already initialized")
+    }
+    session.setState(stateInitializing)
+
+    return &models.MCPResponse{
         ID:      request.ID,
         JSONRPC: "2.0",
         Result: map[string]interface{}{
             "protocolVersion": "2025-03-26",
             "capabilities": map[string]interface{}{
-                "tools": map[string]interface{}{},
+                "tools":     map[string]interface{}{},
+                "resources": map[string]interface{}{
+                    "subscribe":   true,
+                    "listChanged": true,
+                },
+                "prompts":     map[string]interface{}{},
+                "completions": map[string]interface{}{},
+                "logging":     map[string]interface{}{},
             },
             "serverInfo": map[string]interface{}{
                 "name":    "mcp-compose-memory",
@@ -61,385 +128,368 @@ func (h *MCPHandler) handleInitialize(w http.ResponseWriter, request *models.MCP
             },
         },
     }
-    h.sendResponse(w, &response)
 }
 
-func (h *MCPHandler) handleToolsList(w http.ResponseWriter, request *models.MCPRequest) {
-    tools := []map[string]interface{}{
-        {
-            "name":        "create_entities",
-            "description": "Create multiple new entities in the knowledge graph",
-            "inputSchema": map[string]interface{}{
-                "type": "object",
-                "properties": map[string]interface{}{
-                    "entities": map[string]interface{}{
-                        "type": "array",
-                        "items": map[string]interface{}{
-                            "type": "object",
-                            "properties": map[string]interface{}{
-                                "name":         map[string]interface{}{"type": "string", "description": "The name of the entity"},
-                                "entityType":   map[string]interface{}{"type": "string", "description": "The type of the entity"},
-                                "observations": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "An array of observation contents associated with the entity"},
-                            },
-                            "required": []string{"name", "entityType", "observations"},
-                        },
-                    },
-                },
-                "required": []string{"entities"},
-            },
-        },
-        {
-            "name":        "create_relations",
-            "description": "Create multiple new relations between entities in the knowledge graph. Relations should be in active voice",
-            "inputSchema": map[string]interface{}{
-                "type": "object",
-                "properties": map[string]interface{}{
-                    "relations": map[string]interface{}{
-                        "type": "array",
-                        "items": map[string]interface{}{
-                            "type": "object",
-                            "properties": map[string]interface{}{
-                                "from":         map[string]interface{}{"type": "string", "description": "The name of the entity where the relation starts"},
-                                "to":           map[string]interface{}{"type": "string", "description": "The name of the entity where the relation ends"},
-                                "relationType": map[string]interface{}{"type": "string", "description": "The type of the relation"},
-                            },
-                            "required": []string{"from", "to", "relationType"},
-                        },
-                    },
-                },
-                "required": []string{"relations"},
-            },
-        },
-        {
-            "name":        "add_observations",
-            "description": "Add new observations to existing entities in the knowledge graph",
-            "inputSchema": map[string]interface{}{
-                "type": "object",
-                "properties": map[string]interface{}{
-                    "observations": map[string]interface{}{
-                        "type": "array",
-                        "items": map[string]interface{}{
-                            "type": "object",
-                            "properties": map[string]interface{}{
-                                "entityName": map[string]interface{}{"type": "string", "description": "The name of the entity to add the observations to"},
-                                "contents":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "An array of observation contents to add"},
-                            },
-                            "required": []string{"entityName", "contents"},
-                        },
-                    },
-                },
-                "required": []string{"observations"},
-            },
-        },
-        {
-            "name":        "delete_entities",
-            "description": "Delete multiple entities and their associated relations from the knowledge graph",
-            "inputSchema": map[string]interface{}{
-                "type": "object",
-                "properties": map[string]interface{}{
-                    "entityNames": map[string]interface{}{
-                        "type":        "array",
-                        "items":       map[string]interface{}{"type": "string"},
-                        "description": "An array of entity names to delete",
-                    },
-                },
-                "required": []string{"entityNames"},
-            },
-        },
-        {
-            "name":        "delete_observations",
-            "description": "Delete specific observations from entities in the knowledge graph",
-            "inputSchema": map[string]interface{}{
-                "type": "object",
-                "properties": map[string]interface{}{
-                    "deletions": map[string]interface{}{
-                        "type": "array",
-                        "items": map[string]interface{}{
-                            "type": "object",
-                            "properties": map[string]interface{}{
-                                "entityName":   map[string]interface{}{"type": "string", "description": "The name of the entity containing the observations"},
-                                "observations": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "An array of observations to delete"},
-                            },
-                            "required": []string{"entityName", "observations"},
-                        },
-                    },
-                },
-                "required": []string{"deletions"},
-            },
-        },
-        {
-            "name":        "delete_relations",
-            "description": "Delete multiple relations from the knowledge graph",
-            "inputSchema": map[string]interface{}{
-                "type": "object",
-                "properties": map[string]interface{}{
-                    "relations": map[string]interface{}{
-                        "type": "array",
-                        "items": map[string]interface{}{
-                            "type": "object",
-                            "properties": map[string]interface{}{
-                                "from":         map[string]interface{}{"type": "string", "description": "The name of the entity where the relation starts"},
-                                "to":           map[string]interface{}{"type": "string", "description": "The name of the entity where the relation ends"},
-                                "relationType": map[string]interface{}{"type": "string", "description": "The type of the relation"},
-                            },
-                            "required": []string{"from", "to", "relationType"},
-                        },
-                        "description": "An array of relations to delete",
-                    },
-                },
-                "required": []string{"relations"},
-            },
-        },
-        {
-            "name":        "read_graph",
-            "description": "Read the entire knowledge graph",
-            "inputSchema": map[string]interface{}{
-                "type":       "object",
-                "properties": map[string]interface{}{},
-            },
-        },
-        {
-            "name":        "search_nodes",
-            "description": "Search for nodes in the knowledge graph based on a query",
-            "inputSchema": map[string]interface{}{
-                "type": "object",
-                "properties": map[string]interface{}{
-                    "query": map[string]interface{}{"type": "string", "description": "The search query to match against entity names, types, and observation content"},
-                },
-                "required": []string{"query"},
-            },
-        },
-        {
-            "name":        "open_nodes",
-            "description": "Open specific nodes in the knowledge graph by their names",
-            "inputSchema": map[string]interface{}{
-                "type": "object",
-                "properties": map[string]interface{}{
-                    "names": map[string]interface{}{
-                        "type":        "array",
-                        "items":       map[string]interface{}{"type": "string"},
-                        "description": "An array of entity names to retrieve",
-                    },
-                },
-                "required": []string{"names"},
-            },
-        },
+func (h *MCPHandler) handleToolsList(request *models.MCPRequest) *models.MCPResponse {
+    tools := h.tools.definitions()
+
+    cursor, err := listCursor(request)
+    if err == nil && cursor.Offset > 0 && cursor.Offset >= len(tools) {
+        // No page of this list starts there
+        err = errInvalidCursor
+    }
+    if err != nil {
+        return errorResponse(request.ID, -32602, "Invalid params: "+err.Error())
     }
 
-    response := models.MCPResponse{
+    result := map[string]interface{}{}
+    tools = tools[cursor.Offset:]
+    if len(tools) > listPageSize {
+        tools = tools[:listPageSize]
+        result["nextCursor"] = encodeCursor(pageCursor{Offset: cursor.Offset + listPageSize})
+    }
+    result["tools"] = tools
+
+    return &models.MCPResponse{
         ID:      request.ID,
         JSONRPC: "2.0",
-        Result:  map[string]interface{}{"tools": tools},
+        Result:  result,
     }
-    h.sendResponse(w, &response)
 }
 
-func (h *MCPHandler) handleToolsCall(w http.ResponseWriter, request *models.MCPRequest) {
+func (h *MCPHandler) handleToolsCall(ctx context.Context, request *models.MCPRequest) *models.MCPResponse {
     paramsBytes, _ := json.Marshal(request.Params)
     var params models.ToolCallParams
     if err := json.Unmarshal(paramsBytes, &params); err != nil {
-        h.sendError(w, request.ID, -32602, "Invalid params")
-        return
-    }
-
-    var result interface{}
-    var err error
-
-    switch params.Name {
-    case "create_entities":
-        result, err = h.handleCreateEntities(params.Arguments)
-    case "create_relations":
-        result, err = h.handleCreateRelations(params.Arguments)
-    case "add_observations":
-        result, err = h.handleAddObservations(params.Arguments)
-    case "delete_entities":
-        result, err = h.handleDeleteEntities(params.Arguments)
-    case "delete_observations":
-        result, err = h.handleDeleteObservations(params.Arguments)
-    case "delete_relations":
-        result, err = h.handleDeleteRelations(params.Arguments)
-    case "read_graph":
-        result, err = h.handleReadGraph()
-    case "search_nodes":
-        result, err = h.handleSearchNodes(params.Arguments)
-    case "open_nodes":
-        result, err = h.handleOpenNodes(params.Arguments)
-    default:
-        h.sendError(w, request.ID, -32601, "Unknown tool: "+params.Name)
-        return
+        return errorResponse(request.ID, -32602, "Invalid params")
+    }
+
+    tool := h.tools.get(params.Name)
+    if tool == nil {
+        return errorResponse(request.ID, -32602, "Unknown tool: "+params.Name)
+    }
+
+    var timeoutErr error
+    if timeout := h.tools.timeout(tool); timeout > 0 {
+        timeoutErr = knowledge.TimeoutError("Tool "+params.Name, timeout)
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeoutCause(ctx, timeout, timeoutErr)
+        defer cancel()
     }
 
+    knowledge.Log(ctx, knowledge.LogRecord{
+        Level:   knowledge.LevelInfo,
+        Logger:  "tools",
+        Message: "Calling tool " + params.Name,
+        Data:    map[string]interface{}{"tool": params.Name},
+    })
+
+    result, err := tool.handler(ctx, params.Arguments)
     if err != nil {
-        log.Printf("Tool execution error: %v", err)
-        h.sendError(w, request.ID, -32603, err.Error())
-        return
+        // Backends report an expired deadline in their own words
+        if timeoutErr != nil && context.Cause(ctx) == timeoutErr {
+            err = timeoutErr
+        }
+        knowledge.Log(ctx, knowledge.LogRecord{
+            Level:   knowledge.LevelError,
+            Logger:  "tools",
+            Message: "Tool execution error: " + err.Error(),
+            Data:    map[string]interface{}{"tool": params.Name, "error": err.Error()},
+        })
+
+        var argsErr *argumentsError
+        if errors.As(err, &argsErr) || errors.Is(err, knowledge.ErrInvalidArgument) {
+            return errorResponse(request.ID, -32602, "Invalid params: "+err.Error())
+        }
+
+        // Report every other failure of the tool, including those of the
+        // storage backend, to the model rather than as a protocol error
+        result = models.ToolResponse{
+            Content: []models.ToolContent{{Type: "text", Text: err.Error()}},
+            IsError: true,
+        }
     }
 
-    response := models.MCPResponse{
+    return &models.MCPResponse{
         ID:      request.ID,
         JSONRPC: "2.0",
         Result:  result,
     }
-    h.sendResponse(w, &response)
 }
 
-func (h *MCPHandler) handleCreateEntities(args map[string]interface{}) (interface{}, error) {
-    argsBytes, _ := json.Marshal(args)
-    var input models.CreateEntitiesInput
-    if err := json.Unmarshal(argsBytes, &input); err != nil {
-        return nil, err
+func (h *MCPHandler) handleCreateEntities(ctx context.Context, input models.CreateEntitiesInput) (models.CreateEntitiesResult, error) {
+    entities, err := h.manager.CreateEntities(ctx, input.Entities)
+    if err != nil {
+        return models.CreateEntitiesResult{}, err
     }
 
-    entities, err := h.manager.CreateEntities(input.Entities)
+    if entities == nil {
+        entities = []models.Entity{}
+    }
+    return models.CreateEntitiesResult{Entities: entities}, nil
+}
+
+func (h *MCPHandler) handleCreateRelations(ctx context.Context, input models.CreateRelationsInput) (models.CreateRelationsResult, error) {
+    relations, err := h.manager.CreateRelations(ctx, input.Relations)
     if err != nil {
-        return nil, err
+        return models.CreateRelationsResult{}, err
     }
 
-    resultBytes, _ := json.Marshal(entities)
-    return models.ToolResponse{
-        Content: []models.ToolContent{{Type: "text", Text: string(resultBytes)}},
-    }, nil
+    if relations == nil {
+        relations = []models.Relation{}
+    }
+    return models.CreateRelationsResult{Relations: relations}, nil
 }
 
-func (h *MCPHandler) handleCreateRelations(args map[string]interface{}) (interface{}, error) {
-    argsBytes, _ := json.Marshal(args)
-    var input models.CreateRelationsInput
-    if err := json.Unmarshal(argsBytes, &input); err != nil {
-        return nil, err
+func (h *MCPHandler) handleAddObservations(ctx context.Context, input models.AddObservationsInput) (models.AddObservationsResult, error) {
+    results, err := h.manager.AddObservations(ctx, input.Observations)
+    if err != nil {
+        return models.AddObservationsResult{}, err
     }
 
-    relations, err := h.manager.CreateRelations(input.Relations)
-    if err != nil {
-        return nil, err
+    if results == nil {
+        results = []models.ObservationResult{}
+    }
+    for i := range results {
+        if results[i].AddedObservations == nil {
+            results[i].AddedObservations = []string{}
+        }
+    }
+    return models.AddObservationsResult{Results: results}, nil
+}
+
+func (h *MCPHandler) handleDeleteEntities(ctx context.Context, input models.DeleteEntitiesInput) (models.DeleteResult, error) {
+    if err := h.manager.DeleteEntities(ctx, input.EntityNames); err != nil {
+        return models.DeleteResult{}, err
     }
 
-    resultBytes, _ := json.Marshal(relations)
-    return models.ToolResponse{
-        Content: []models.ToolContent{{Type: "text", Text: string(resultBytes)}},
-    }, nil
+    return models.DeleteResult{Message: "Entities deleted successfully"}, nil
 }
 
-func (h *MCPHandler) handleAddObservations(args map[string]interface{}) (interface{}, error) {
-    argsBytes, _ := json.Marshal(args)
-    var input models.AddObservationsInput
-    if err := json.Unmarshal(argsBytes, &input); err != nil {
-        return nil, err
+func (h *MCPHandler) handleDeleteObservations(ctx context.Context, input models.DeleteObservationsInput) (models.DeleteResult, error) {
+    if err := h.manager.DeleteObservations(ctx, input.Deletions); err != nil {
+        return models.DeleteResult{}, err
     }
 
-    results, err := h.manager.AddObservations(input.Observations)
-    if err != nil {
-        return nil, err
+    return models.DeleteResult{Message: "Observations deleted successfully"}, nil
+}
+
+func (h *MCPHandler) handleDeleteRelations(ctx context.Context, input models.DeleteRelationsInput) (models.DeleteResult, error) {
+    if err := h.manager.DeleteRelations(ctx, input.Relations); err != nil {
+        return models.DeleteResult{}, err
     }
 
-    resultBytes, _ := json.Marshal(results)
-    return models.ToolResponse{
-        Content: []models.ToolContent{{Type: "text", Text: string(resultBytes)}},
-    }, nil
+    return models.DeleteResult{Message: "Relations deleted successfully"}, nil
 }
 
-func (h *MCPHandler) handleDeleteEntities(args map[string]interface{}) (interface{}, error) {
-    argsBytes, _ := json.Marshal(args)
-    var input models.DeleteEntitiesInput
-    if err := json.Unmarshal(argsBytes, &input); err != nil {
-        return nil, err
+func (h *MCPHandler) handleReadGraph(ctx context.Context, input models.ReadGraphInput) (models.GraphPage, error) {
+    // Without a limit or cursor the whole graph is returned, as before
+    // pagination existed
+    if input.Limit == 0 && input.Cursor == "" {
+        graph, err := h.manager.ReadGraph(ctx)
+        if err != nil {
+            return models.GraphPage{}, err
+        }
+        return graphPageOf(graph, false), nil
     }
 
-    if err := h.manager.DeleteEntities(input.EntityNames); err != nil {
-        return nil, err
+    page, err := graphPage(input.Limit, input.Cursor)
+    if err != nil {
+        return models.GraphPage{}, err
     }
 
-    return models.ToolResponse{
-        Content: []models.ToolContent{{Type: "text", Text: "Entities deleted successfully"}},
-    }, nil
+    graph, more, err := h.manager.ReadGraphPage(ctx, page)
+    if err != nil {
+        return models.GraphPage{}, err
+    }
+    return graphPageOf(graph, more), nil
 }
 
-func (h *MCPHandler) handleDeleteObservations(args map[string]interface{}) (interface{}, error) {
-    argsBytes, _ := json.Marshal(args)
-    var input models.DeleteObservationsInput
-    if err := json.Unmarshal(argsBytes, &input); err != nil {
-        return nil, err
+func (h *MCPHandler) handleSearchNodes(ctx context.Context, input models.SearchNodesInput) (models.GraphPage, error) {
+    if input.Limit == 0 && input.Cursor == "" {
+        graph, err := h.manager.SearchNodes(ctx, input.Query)
+        if err != nil {
+            return models.GraphPage{}, err
+        }
+        return graphPageOf(graph, false), nil
     }
 
-    if err := h.manager.DeleteObservations(input.Deletions); err != nil {
-        return nil, err
+    page, err := graphPage(input.Limit, input.Cursor)
+    if err != nil {
+        return models.GraphPage{}, err
     }
 
-    return models.ToolResponse{
-        Content: []models.ToolContent{{Type: "text", Text: "Observations deleted successfully"}},
-    }, nil
+    graph, more, err := h.manager.SearchNodesPage(ctx, input.Query, page)
+    if err != nil {
+        return models.GraphPage{}, err
+    }
+    return graphPageOf(graph, more), nil
 }
 
-func (h *MCPHandler) handleDeleteRelations(args map[string]interface{}) (interface{}, error) {
-    argsBytes, _ := json.Marshal(args)
-    var input models.DeleteRelationsInput
-    if err := json.Unmarshal(argsBytes, &input); err != nil {
-        return nil, err
+func (h *MCPHandler) handleOpenNodes(ctx context.Context, input models.OpenNodesInput) (models.KnowledgeGraph, error) {
+    graph, err := h.manager.OpenNodes(ctx, input.Names)
+    if err != nil {
+        return models.KnowledgeGraph{}, err
     }
 
-    if err := h.manager.DeleteRelations(input.Relations); err != nil {
-        return nil, err
+    normalizeGraph(graph)
+    return *graph, nil
+}
+
+func (h *MCPHandler) handleGetNeighborhood(ctx context.Context, input models.GetNeighborhoodInput) (models.Neighborhood, error) {
+    depth := 1
+    if input.Depth != nil {
+        depth = *input.Depth
     }
 
-    return models.ToolResponse{
-        Content: []models.ToolContent{{Type: "text", Text: "Relations deleted successfully"}},
-    }, nil
+    neighborhood, err := h.manager.Neighborhood(ctx, knowledge.NeighborhoodQuery{
+        Names:         input.Names,
+        Depth:         depth,
+        Direction:     knowledge.Direction(input.Direction),
+        RelationTypes: input.RelationTypes,
+    })
+    if err != nil {
+        return models.Neighborhood{}, err
+    }
+
+    normalizeGraph(&neighborhood.KnowledgeGraph)
+    return *neighborhood, nil
 }
 
-func (h *MCPHandler) handleReadGraph() (interface{}, error) {
-    graph, err := h.manager.ReadGraph()
-    if err != nil {
-        return nil, err
+const (
+    // defaultPathLength is the longest path find_paths looks for unless
+    // maxLength is given
+    defaultPathLength = 6
+
+    // defaultPathCount is how many paths of each kind find_paths returns
+    // unless maxPaths is given
+    defaultPathCount = 100
+
+    // defaultStatsLimit is how many entities, components, and orphans
+    // graph_stats lists unless limit is given
+    defaultStatsLimit = 20
+
+    // defaultCommunityLimit is how many communities, and members of each,
+    // detect_communities lists unless limit is given
+    defaultCommunityLimit = 20
+
+    // defaultCommunitySize is the least size of the communities
+    // detect_communities lists unless minSize is given, leaving out the
+    // entities without relations
+    defaultCommunitySize = 2
+)
+
+func (h *MCPHandler) handleFindPaths(ctx context.Context, input models.FindPathsInput) (models.FindPathsResult, error) {
+    query := knowledge.PathQuery{
+        From:          input.From,
+        To:            input.To,
+        Direction:     knowledge.Direction(input.Direction),
+        RelationTypes: input.RelationTypes,
+        MaxLength:     input.MaxLength,
+        AllPaths:      input.AllPaths,
+        MaxPaths:      input.MaxPaths,
+    }
+    if query.MaxLength == 0 {
+        query.MaxLength = defaultPathLength
+    }
+    if query.MaxPaths == 0 {
+        query.MaxPaths = defaultPathCount
     }
 
-    resultBytes, _ := json.Marshal(graph)
-    return models.ToolResponse{
-        Content: []models.ToolContent{{Type: "text", Text: string(resultBytes)}},
-    }, nil
+    result, err := h.manager.FindPaths(ctx, query)
+    if err != nil {
+        return models.FindPathsResult{}, err
+    }
+    return *result, nil
 }
 
-func (h *MCPHandler) handleSearchNodes(args map[string]interface{}) (interface{}, error) {
-    argsBytes, _ := json.Marshal(args)
-    var input models.SearchNodesInput
-    if err := json.Unmarshal(argsBytes, &input); err != nil {
-        return nil, err
+func (h *MCPHandler) handleGraphStats(ctx context.Context, input models.GraphStatsInput) (models.GraphStats, error) {
+    limit := input.Limit
+    if limit == 0 {
+        limit = defaultStatsLimit
     }
 
-    graph, err := h.manager.SearchNodes(input.Query)
+    stats, err := h.manager.GraphStats(ctx)
     if err != nil {
-        return nil, err
+        return models.GraphStats{}, err
+    }
+    return stats.Top(limit), nil
+}
+
+func (h *MCPHandler) handleDetectCommunities(ctx context.Context, input models.DetectCommunitiesInput) (models.Communities, error) {
+    limit, minSize := input.Limit, input.MinSize
+    if limit == 0 {
+        limit = defaultCommunityLimit
+    }
+    if minSize == 0 {
+        minSize = defaultCommunitySize
     }
 
-    resultBytes, _ := json.Marshal(graph)
-    return models.ToolResponse{
-        Content: []models.ToolContent{{Type: "text", Text: string(resultBytes)}},
-    }, nil
+    communities, err := h.manager.DetectCommunities(ctx)
+    if err != nil {
+        return models.Communities{}, err
+    }
+    return communities.Top(limit, minSize), nil
 }
 
-func (h *MCPHandler) handleOpenNodes(args map[string]interface{}) (interface{}, error) {
-    argsBytes, _ := json.Marshal(args)
-    var input models.OpenNodesInput
-    if err := json.Unmarshal(argsBytes, &input); err != nil {
-        return nil, err
+// graphPage turns the limit and cursor arguments of a paginated tool into
+// the page to read
+func graphPage(limit int, cursor string) (knowledge.Page, error) {
+    limit, err := pageLimit(limit)
+    if err != nil {
+        return knowledge.Page{}, &argumentsError{err: err}
     }
 
-    graph, err := h.manager.OpenNodes(input.Names)
+    c, err := decodeCursor(cursor)
     if err != nil {
-        return nil, err
+        return knowledge.Page{}, &argumentsError{err: err}
+    }
+
+    return knowledge.Page{After: c.After, Limit: limit}, nil
+}
+
+// graphPageOf returns graph as a tool result, with a cursor for the next
+// page if more follow
+func graphPageOf(graph *models.KnowledgeGraph, more bool) models.GraphPage {
+    normalizeGraph(graph)
+    result := models.GraphPage{KnowledgeGraph: *graph}
+    if more {
+        last := result.Entities[len(result.Entities)-1]
+        result.NextCursor = encodeCursor(pageCursor{After: last.Name})
+    }
+    return result
+}
+
+// normalizeGraph replaces nil slices so that they encode as empty arrays,
+// as the output schema requires
+func normalizeGraph(graph *models.KnowledgeGraph) {
+    if graph.Entities == nil {
+        graph.Entities = []models.Entity{}
+    }
+    if graph.Relations == nil {
+        graph.Relations = []models.Relation{}
     }
+}
 
-    resultBytes, _ := json.Marshal(graph)
-    return models.ToolResponse{
-        Content: []models.ToolContent{{Type: "text", Text: string(resultBytes)}},
-    }, nil
+// argumentsError reports tool arguments that do not match the tool's input type
+type argumentsError struct {
+    err error
 }
 
-func (h *MCPHandler) sendResponse(w http.ResponseWriter, response *models.MCPResponse) {
-    w.WriteHeader(http.StatusOK)
-    json.NewEncoder(w).Encode(response)
+func (e *argumentsError) Error() string {
+    return e.err.Error()
+}
+
+func decodeArguments(args map[string]interface{}, input interface{}) error {
+    argsBytes, _ := json.Marshal(args)
+    if err := json.Unmarshal(argsBytes, input); err != nil {
+        return &argumentsError{err: err}
+    }
+    return nil
 }
 
-func (h *MCPHandler) sendError(w http.ResponseWriter, id interface{}, code int, message string) {
-    response := models.MCPResponse{
+func errorResponse(id interface{}, code int, message string) *models.MCPResponse {
+    return &models.MCPResponse{
         ID:      id,
         JSONRPC: "2.0",
         Error: &models.MCPError{
@@ -447,6 +497,4 @@ func (h *MCPHandler) sendError(w http.ResponseWriter, id interface{}, code int,
             Message: message,
         },
     }
-    w.WriteHeader(http.StatusOK) // JSON-RPC errors use 200 status
-    json.NewEncoder(w).Encode(response)
 }
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "mcp-compose-memory/internal/knowledge"
+    "mcp-compose-memory/internal/models"
+    "testing"
+)
+
+// newReadySession returns a session of h that has completed the handshake
+func newReadySession(h *MCPHandler) *Session {
+    session := newSession(newSessionID())
+    session.setState(stateReady)
+    h.sessions.add(session)
+    return session
+}
+
+// call sends a request to h and returns its response decoded from JSON, as
+// a client would see it
+func call(t *testing.T, h *MCPHandler, session *Session, method string, params interface{}) map[string]interface{} {
+    t.Helper()
+
+    body, err := json.Marshal(map[string]interface{}{
+        "jsonrpc": "2.0",
+        "id":      1,
+        "method":  method,
+        "params":  params,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    reply := h.HandleMessage(context.Background(), session, body)
+    if reply == nil {
+        t.Fatalf("%s got no response", method)
+    }
+    encoded, err := json.Marshal(reply)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var response map[string]interface{}
+    if err := json.Unmarshal(encoded, &response); err != nil {
+        t.Fatal(err)
+    }
+    return response
+}
+
+// errorCode returns the JSON-RPC error code of a response, or 0 if it has
+// a result
+func errorCode(response map[string]interface{}) int {
+    e, ok := response["error"].(map[string]interface{})
+    if !ok {
+        return 0
+    }
+    return int(e["code"].(float64))
+}
+
+// result returns the result of a response, failing the test on an error
+func result(t *testing.T, response map[string]interface{}) map[string]interface{} {
+    t.Helper()
+
+    r, ok := response["result"].(map[string]interface{})
+    if !ok {
+        t.Fatalf("response has no result: %v", response)
+    }
+    return r
+}
+
+// failingStore fails every read of the whole graph, as a broken database
+// connection would
+type failingStore struct {
+    *knowledge.MemoryStore
+}
+
+var errBackend = errors.New("connection refused")
+
+func (s failingStore) ReadGraph(ctx context.Context) (*models.KnowledgeGraph, error) {
+    return nil, errBackend
+}
+
+func TestToolsCallErrors(t *testing.T) {
+    tests := []struct {
+        name      string
+        params    map[string]interface{}
+        wantCode  int
+        wantError string
+    }{
+        {
+            name:     "unknown tool",
+            params:   map[string]interface{}{"name": "no_such_tool", "arguments": map[string]interface{}{}},
+            wantCode: -32602,
+        },
+        {
+            name:     "invalid arguments",
+            params:   map[string]interface{}{"name": "open_nodes", "arguments": map[string]interface{}{"names": "alice"}},
+            wantCode: -32602,
+        },
+        {
+            name: "missing entity",
+            params: map[string]interface{}{"name": "add_observations", "arguments": map[string]interface{}{
+                "observations": []interface{}{map[string]interface{}{"entityName": "nobody", "contents": []interface{}{"x"}}},
+            }},
+            wantError: "entity with name nobody not found",
+        },
+        {
+            name:      "backend failure",
+            params:    map[string]interface{}{"name": "read_graph", "arguments": map[string]interface{}{}},
+            wantError: errBackend.Error(),
+        },
+    }
+
+    h := NewMCPHandler(knowledge.NewManager(failingStore{knowledge.NewMemoryStore()}))
+    session := newReadySession(h)
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            response := call(t, h, session, "tools/call", tt.params)
+            if tt.wantCode != 0 {
+                if code := errorCode(response); code != tt.wantCode {
+                    t.Fatalf("error code = %d, want %d", code, tt.wantCode)
+                }
+                return
+            }
+
+            r := result(t, response)
+            if r["isError"] != true {
+                t.Fatalf("isError = %v, want true", r["isError"])
+            }
+            content := r["content"].([]interface{})[0].(map[string]interface{})
+            if content["text"] != tt.wantError {
+                t.Errorf("text = %q, want %q", content["text"], tt.wantError)
+            }
+        })
+    }
+}
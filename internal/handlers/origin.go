@@ -0,0 +1,65 @@
+package handlers
+
+import (
+    "net"
+    "net/http"
+    "net/url"
+    "strings"
+)
+
+// OriginPolicy decides which browser origins may use the HTTP transport.
+// The transport must validate the Origin header so that a web page cannot
+// reach a server on the user's machine through DNS rebinding. Requests
+// without an Origin header do not come from a browser and are allowed.
+type OriginPolicy struct {
+    any     bool
+    allowed map[string]bool
+}
+
+// NewOriginPolicy allows the listed origins, such as https://app.example.com.
+// "*" allows every origin. With no origins listed, only pages served from
+// the loopback interface are allowed.
+func NewOriginPolicy(origins []string) *OriginPolicy {
+    p := &OriginPolicy{allowed: make(map[string]bool)}
+    for _, origin := range origins {
+        origin = normalizeOrigin(origin)
+        if origin == "*" {
+            p.any = true
+        }
+        p.allowed[origin] = true
+    }
+    return p
+}
+
+// Allows reports whether a request may be served given its Origin header
+func (p *OriginPolicy) Allows(r *http.Request) bool {
+    origin := r.Header.Get("Origin")
+    if origin == "" || p.any {
+        return true
+    }
+    if len(p.allowed) > 0 {
+        return p.allowed[normalizeOrigin(origin)]
+    }
+    return isLoopbackOrigin(origin)
+}
+
+func normalizeOrigin(origin string) string {
+    return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(origin)), "/")
+}
+
+// isLoopbackOrigin reports whether origin is a page served from this
+// machine by address, such as http://localhost:5173. The host name of a
+// page on a rebound domain does not resolve here, so it is not trusted.
+func isLoopbackOrigin(origin string) bool {
+    u, err := url.Parse(origin)
+    if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+        return false
+    }
+
+    host := u.Hostname()
+    if strings.EqualFold(host, "localhost") {
+        return true
+    }
+    ip := net.ParseIP(host)
+    return ip != nil && ip.IsLoopback()
+}
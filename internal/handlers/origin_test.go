@@ -0,0 +1,42 @@
+package handlers
+
+import (
+    "net/http/httptest"
+    "testing"
+)
+
+func TestOriginPolicy(t *testing.T) {
+    tests := []struct {
+        name    string
+        allowed []string
+        origin  string
+        want    bool
+    }{
+        {"no origin", nil, "", true},
+        {"localhost", nil, "http://localhost:5173", true},
+        {"loopback address", nil, "http://127.0.0.1:3000", true},
+        {"loopback IPv6", nil, "http://[::1]:3000", true},
+        {"remote page", nil, "https://evil.example.com", false},
+        {"rebound name", nil, "http://localhost.evil.example.com", false},
+        {"opaque origin", nil, "null", false},
+        {"other scheme", nil, "file://localhost", false},
+        {"listed", []string{"https://app.example.com"}, "https://app.example.com", true},
+        {"listed ignoring case and slash", []string{"https://App.example.com/"}, "https://app.example.com", true},
+        {"unlisted", []string{"https://app.example.com"}, "https://evil.example.com", false},
+        {"list replaces loopback", []string{"https://app.example.com"}, "http://localhost:5173", false},
+        {"listed loopback", []string{"http://localhost:5173"}, "http://localhost:5173", true},
+        {"any", []string{"*"}, "https://evil.example.com", true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            r := httptest.NewRequest("POST", "/", nil)
+            if tt.origin != "" {
+                r.Header.Set("Origin", tt.origin)
+            }
+            if got := NewOriginPolicy(tt.allowed).Allows(r); got != tt.want {
+                t.Errorf("Allows(%q) = %v, want %v", tt.origin, got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "mcp-compose-memory/internal/models"
+)
+
+const (
+    // defaultPageLimit is the page size of read_graph and search_nodes when
+    // a cursor is given without a limit
+    defaultPageLimit = 100
+
+    // maxPageLimit is the largest limit read_graph and search_nodes accept,
+    // matching the maximum in the jsonschema tags of their input types
+    maxPageLimit = 1000
+
+    // listPageSize is the page size of tools/list and resources/list
+    listPageSize = 100
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor is the position a cursor resumes from. Graph pages resume
+// after an entity name, which stays valid as entities are added or
+// removed; the tool list resumes at an offset.
+type pageCursor struct {
+    After  string `json:"a,omitempty"`
+    Offset int    `json:"o,omitempty"`
+}
+
+// encodeCursor returns the opaque form of a cursor handed to clients
+func encodeCursor(c pageCursor) string {
+    data, _ := json.Marshal(c)
+    return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string) (pageCursor, error) {
+    var c pageCursor
+    if cursor == "" {
+        return c, nil
+    }
+
+    data, err := base64.RawURLEncoding.DecodeString(cursor)
+    if err != nil {
+        return c, errInvalidCursor
+    }
+    if err := json.Unmarshal(data, &c); err != nil || c.Offset < 0 {
+        return c, errInvalidCursor
+    }
+    return c, nil
+}
+
+// pageLimit validates the limit argument of a paginated tool
+func pageLimit(limit int) (int, error) {
+    switch {
+    case limit < 0 || limit > maxPageLimit:
+        return 0, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+    case limit == 0:
+        return defaultPageLimit, nil
+    default:
+        return limit, nil
+    }
+}
+
+// listCursor decodes the cursor of a paginated list request
+func listCursor(request *models.MCPRequest) (pageCursor, error) {
+    paramsBytes, _ := json.Marshal(request.Params)
+    var params models.PaginatedParams
+    if err := json.Unmarshal(paramsBytes, &params); err != nil {
+        return pageCursor{}, err
+    }
+    return decodeCursor(params.Cursor)
+}
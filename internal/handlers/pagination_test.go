@@ -0,0 +1,106 @@
+package handlers
+
+import (
+    "fmt"
+    "mcp-compose-memory/internal/knowledge"
+    "reflect"
+    "testing"
+)
+
+// newPagedHandler returns a handler over a graph of n entities named e000,
+// e001 and so on, and a ready session of it
+func newPagedHandler(t *testing.T, n int) (*MCPHandler, *Session) {
+    t.Helper()
+
+    h := NewMCPHandler(knowledge.NewManager(knowledge.NewMemoryStore()))
+    session := newReadySession(h)
+
+    var entities []interface{}
+    for i := 0; i < n; i++ {
+        entities = append(entities, map[string]interface{}{"name": fmt.Sprintf("e%03d", i), "entityType": "thing", "observations": []interface{}{}})
+    }
+    response := call(t, h, session, "tools/call", map[string]interface{}{
+        "name":      "create_entities",
+        "arguments": map[string]interface{}{"entities": entities},
+    })
+    if r := result(t, response); r["isError"] == true {
+        t.Fatalf("create_entities failed: %v", r)
+    }
+    return h, session
+}
+
+func TestReadGraphPages(t *testing.T) {
+    h, session := newPagedHandler(t, 5)
+
+    var names []string
+    arguments := map[string]interface{}{"limit": 2}
+    for pages := 1; ; pages++ {
+        r := result(t, call(t, h, session, "tools/call", map[string]interface{}{"name": "read_graph", "arguments": arguments}))
+        page := r["structuredContent"].(map[string]interface{})
+        for _, entity := range page["entities"].([]interface{}) {
+            names = append(names, entity.(map[string]interface{})["name"].(string))
+        }
+
+        cursor, ok := page["nextCursor"].(string)
+        if !ok {
+            if pages != 3 {
+                t.Errorf("read %d pages, want 3", pages)
+            }
+            break
+        }
+        arguments = map[string]interface{}{"limit": 2, "cursor": cursor}
+    }
+
+    if want := []string{"e000", "e001", "e002", "e003", "e004"}; !reflect.DeepEqual(names, want) {
+        t.Errorf("read %v, want %v", names, want)
+    }
+}
+
+func TestResourcesListPages(t *testing.T) {
+    h, session := newPagedHandler(t, listPageSize+1)
+
+    first := result(t, call(t, h, session, "resources/list", map[string]interface{}{}))
+    cursor, ok := first["nextCursor"].(string)
+    if !ok {
+        t.Fatal("first page has no nextCursor")
+    }
+    // The graph resource comes first, then a page of entities
+    if n := len(first["resources"].([]interface{})); n != listPageSize+1 {
+        t.Errorf("first page holds %d resources, want %d", n, listPageSize+1)
+    }
+
+    last := result(t, call(t, h, session, "resources/list", map[string]interface{}{"cursor": cursor}))
+    if _, ok := last["nextCursor"]; ok {
+        t.Error("last page has a nextCursor")
+    }
+    resources := last["resources"].([]interface{})
+    if len(resources) != 1 || resources[0].(map[string]interface{})["uri"] != entityURI(fmt.Sprintf("e%03d", listPageSize)) {
+        t.Errorf("last page = %v, want only the last entity", resources)
+    }
+}
+
+func TestInvalidCursors(t *testing.T) {
+    h, session := newPagedHandler(t, 3)
+
+    stale := encodeCursor(pageCursor{Offset: listPageSize})
+    tests := []struct {
+        name   string
+        method string
+        params map[string]interface{}
+    }{
+        {"tools/list garbage", "tools/list", map[string]interface{}{"cursor": "!!"}},
+        {"tools/list past the end", "tools/list", map[string]interface{}{"cursor": stale}},
+        {"resources/list garbage", "resources/list", map[string]interface{}{"cursor": "!!"}},
+        {"read_graph garbage", "tools/call", map[string]interface{}{"name": "read_graph", "arguments": map[string]interface{}{"cursor": "!!"}}},
+        {"search_nodes not JSON", "tools/call", map[string]interface{}{"name": "search_nodes", "arguments": map[string]interface{}{"query": "e", "cursor": "bm90IGpzb24"}}},
+        {"read_graph limit too large", "tools/call", map[string]interface{}{"name": "read_graph", "arguments": map[string]interface{}{"limit": maxPageLimit + 1}}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if code := errorCode(call(t, h, session, tt.method, tt.params)); code != -32602 {
+                t.Errorf("error code = %d, want -32602", code)
+            }
+        })
+    }
+}
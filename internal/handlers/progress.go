@@ -0,0 +1,102 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "log"
+    "mcp-compose-memory/internal/knowledge"
+    "mcp-compose-memory/internal/models"
+    "sync"
+    "time"
+)
+
+// progressInterval is the least time between two progress notifications
+// for one request, so that per-item progress does not flood the client
+const progressInterval = 100 * time.Millisecond
+
+// errRequestCancelled is the cause of a request context cancelled by the
+// client's notifications/cancelled
+var errRequestCancelled = errors.New("request cancelled by the client")
+
+// requestMeta is the _meta member of request params
+type requestMeta struct {
+    Meta struct {
+        ProgressToken interface{} `json:"progressToken"`
+    } `json:"_meta"`
+}
+
+// handleRequest dispatches a request under a context the client can cancel
+// with notifications/cancelled, sending its log records to the client and
+// reporting progress if the request carries a progress token. A cancelled
+// request gets no response.
+func (h *MCPHandler) handleRequest(ctx context.Context, session *Session, request *models.MCPRequest) *models.MCPResponse {
+    ctx, cancel := context.WithCancelCause(ctx)
+    defer cancel(nil)
+
+    untrack := session.track(request.ID, cancel)
+    defer untrack()
+
+    ctx = knowledge.WithLogger(ctx, sessionLogger(ctx, session))
+
+    paramsBytes, _ := json.Marshal(request.Params)
+    var meta requestMeta
+    json.Unmarshal(paramsBytes, &meta)
+    if token := meta.Meta.ProgressToken; token != nil {
+        ctx = knowledge.WithProgress(ctx, progressNotifier(ctx, session, token))
+    }
+
+    response := h.dispatch(ctx, session, request)
+
+    if context.Cause(ctx) == errRequestCancelled {
+        log.Printf("Request %v in session %s was cancelled", request.ID, session.ID)
+        return nil
+    }
+    return response
+}
+
+// progressNotifier sends notifications/progress for token, at most once
+// per progressInterval except for the final update
+func progressNotifier(ctx context.Context, session *Session, token interface{}) knowledge.ProgressFunc {
+    var mu sync.Mutex
+    var last time.Time
+    sent := -1
+
+    return func(done, total int) {
+        mu.Lock()
+        defer mu.Unlock()
+
+        final := total > 0 && done >= total
+        if done <= sent || (!final && time.Since(last) < progressInterval) {
+            return
+        }
+        sent, last = done, time.Now()
+
+        params := map[string]interface{}{
+            "progressToken": token,
+            "progress":      done,
+        }
+        if total > 0 {
+            params["total"] = total
+        }
+        session.notifyRequest(ctx, "notifications/progress", params)
+    }
+}
+
+func (h *MCPHandler) handleCancelled(session *Session, notification *models.MCPRequest) {
+    paramsBytes, _ := json.Marshal(notification.Params)
+    var params struct {
+        RequestID interface{} `json:"requestId"`
+        Reason    string      `json:"reason"`
+    }
+    if err := json.Unmarshal(paramsBytes, &params); err != nil {
+        log.Printf("Ignoring malformed cancellation from session %s", session.ID)
+        return
+    }
+
+    // The request may well have finished already, in which case the
+    // cancellation is ignored
+    if session.cancelRequest(params.RequestID) {
+        log.Printf("Session %s cancelled request %v: %s", session.ID, params.RequestID, params.Reason)
+    }
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "mcp-compose-memory/internal/knowledge"
+    "mcp-compose-memory/internal/models"
+    "testing"
+    "time"
+)
+
+// blockingStore holds every read of the whole graph until its context is
+// done, as a slow query would
+type blockingStore struct {
+    *knowledge.MemoryStore
+    started chan struct{}
+}
+
+func (s blockingStore) ReadGraph(ctx context.Context) (*models.KnowledgeGraph, error) {
+    close(s.started)
+    <-ctx.Done()
+    return nil, ctx.Err()
+}
+
+func TestCancelledRequest(t *testing.T) {
+    store := blockingStore{MemoryStore: knowledge.NewMemoryStore(), started: make(chan struct{})}
+    h := NewMCPHandler(knowledge.NewManager(store))
+    session := newReadySession(h)
+
+    replies := make(chan interface{}, 1)
+    go func() {
+        replies <- h.HandleMessage(context.Background(), session, []byte(`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"read_graph","arguments":{}}}`))
+    }()
+
+    select {
+    case <-store.started:
+    case <-time.After(5 * time.Second):
+        t.Fatal("read_graph did not start")
+    }
+    if reply := h.HandleMessage(context.Background(), session, []byte(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7,"reason":"test"}}`)); reply != nil {
+        t.Errorf("notifications/cancelled got a response: %v", reply)
+    }
+
+    select {
+    case reply := <-replies:
+        // A cancelled request gets no response
+        if reply != nil {
+            t.Errorf("cancelled request got a response: %v", reply)
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatal("read_graph kept running after it was cancelled")
+    }
+}
+
+func TestProgressNotifications(t *testing.T) {
+    h := newTestHandler()
+    session, messages := newWriterSession(h)
+    defer h.sessions.remove(session.ID)
+
+    // Enough entities for the scan to report progress
+    var entities []models.Entity
+    for i := 0; i < 2500; i++ {
+        entities = append(entities, models.Entity{Name: fmt.Sprintf("e%04d", i), EntityType: "thing", Observations: []string{}})
+    }
+    if _, err := h.manager.CreateEntities(context.Background(), entities); err != nil {
+        t.Fatal(err)
+    }
+    // Drain the list_changed notification of the write
+    notifications(messages)
+
+    reply := h.HandleMessage(context.Background(), session, []byte(`{"jsonrpc":"2.0","id":8,"method":"tools/call","params":{"name":"read_graph","arguments":{},"_meta":{"progressToken":"read-1"}}}`))
+    if reply == nil {
+        t.Fatal("read_graph got no response")
+    }
+
+    // Progress is written to the session before the response is returned
+    var progress []map[string]interface{}
+drain:
+    for {
+        select {
+        case message := <-messages:
+            if message["method"] == "notifications/progress" {
+                progress = append(progress, message["params"].(map[string]interface{}))
+            }
+        default:
+            break drain
+        }
+    }
+
+    if len(progress) == 0 {
+        t.Fatal("no progress notifications")
+    }
+    for _, params := range progress {
+        if params["progressToken"] != "read-1" || params["total"] != float64(len(entities)) {
+            t.Errorf("progress = %v, want token read-1 and total %d", params, len(entities))
+        }
+    }
+}
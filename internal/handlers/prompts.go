@@ -0,0 +1,113 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "log"
+    "mcp-compose-memory/internal/knowledge"
+    "mcp-compose-memory/internal/models"
+    "mcp-compose-memory/internal/prompts"
+    "text/template"
+)
+
+// AddPrompts makes prompts available through prompts/list and prompts/get.
+// A prompt replaces any existing prompt with the same name.
+func (h *MCPHandler) AddPrompts(added ...*prompts.Prompt) {
+    for _, prompt := range added {
+        replaced := false
+        for i, existing := range h.prompts {
+            if existing.Name == prompt.Name {
+                log.Printf("Prompt %s replaces an existing prompt", prompt.Name)
+                h.prompts[i] = prompt
+                replaced = true
+                break
+            }
+        }
+        if !replaced {
+            h.prompts = append(h.prompts, prompt)
+        }
+    }
+}
+
+func (h *MCPHandler) findPrompt(name string) *prompts.Prompt {
+    for _, prompt := range h.prompts {
+        if prompt.Name == name {
+            return prompt
+        }
+    }
+    return nil
+}
+
+// promptFuncs are the template functions prompts use to pre-fill
+// themselves with knowledge graph content, rendered as JSON in the same
+// shape as the tool results
+func (h *MCPHandler) promptFuncs(ctx context.Context) template.FuncMap {
+    asJSON := func(graph *models.KnowledgeGraph, err error) (string, error) {
+        if err != nil {
+            return "", err
+        }
+        normalizeGraph(graph)
+        data, err := json.MarshalIndent(graph, "", "  ")
+        return string(data), err
+    }
+
+    return template.FuncMap{
+        "openNodes": func(names ...string) (string, error) {
+            return asJSON(h.manager.OpenNodes(ctx, names))
+        },
+        "searchNodes": func(query string) (string, error) {
+            return asJSON(h.manager.SearchNodes(ctx, query))
+        },
+        "entitiesOfType": func(entityType string) (string, error) {
+            return asJSON(h.manager.EntitiesOfType(ctx, entityType))
+        },
+    }
+}
+
+func (h *MCPHandler) handlePromptsList(request *models.MCPRequest) *models.MCPResponse {
+    infos := make([]models.PromptInfo, 0, len(h.prompts))
+    for _, prompt := range h.prompts {
+        infos = append(infos, prompt.Info())
+    }
+
+    return &models.MCPResponse{
+        ID:      request.ID,
+        JSONRPC: "2.0",
+        Result:  map[string]interface{}{"prompts": infos},
+    }
+}
+
+func (h *MCPHandler) handlePromptsGet(ctx context.Context, request *models.MCPRequest) *models.MCPResponse {
+    paramsBytes, _ := json.Marshal(request.Params)
+    var params models.PromptGetParams
+    if err := json.Unmarshal(paramsBytes, &params); err != nil {
+        return errorResponse(request.ID, -32602, "Invalid params")
+    }
+
+    prompt := h.findPrompt(params.Name)
+    if prompt == nil {
+        return errorResponse(request.ID, -32602, "Unknown prompt: "+params.Name)
+    }
+
+    text, err := prompt.Render(params.Arguments, h.promptFuncs(ctx))
+    if errors.Is(err, prompts.ErrMissingArgument) || errors.Is(err, knowledge.ErrInvalidArgument) {
+        return errorResponse(request.ID, -32602, "Invalid params: "+err.Error())
+    }
+    if err != nil {
+        // The template or the store failed, not the client
+        return errorResponse(request.ID, -32603, "Internal error: "+err.Error())
+    }
+
+    return &models.MCPResponse{
+        ID:      request.ID,
+        JSONRPC: "2.0",
+        Result: map[string]interface{}{
+            "description": prompt.Description,
+            "messages": []models.PromptMessage{{
+                Role:    "user",
+                Content: models.ToolContent{Type: "text", Text: text},
+            }},
+        },
+    }
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+    "mcp-compose-memory/internal/knowledge"
+    "mcp-compose-memory/internal/prompts"
+    "strings"
+    "testing"
+)
+
+func TestPromptsGetErrors(t *testing.T) {
+    h := NewMCPHandler(knowledge.NewManager(failingStore{knowledge.NewMemoryStore()}))
+    h.AddPrompts(&prompts.Prompt{Name: "broken", Template: `{{index .name 99}}`})
+    session := newReadySession(h)
+
+    tests := []struct {
+        name     string
+        params   map[string]interface{}
+        wantCode int
+    }{
+        {"unknown prompt", map[string]interface{}{"name": "no_such_prompt"}, -32602},
+        {"missing argument", map[string]interface{}{"name": "recall", "arguments": map[string]interface{}{}}, -32602},
+        {"blank argument", map[string]interface{}{"name": "recall", "arguments": map[string]interface{}{"name": " "}}, -32602},
+        {"template failure", map[string]interface{}{"name": "broken", "arguments": map[string]interface{}{"name": "x"}}, -32603},
+        {"store failure", map[string]interface{}{"name": "consolidate", "arguments": map[string]interface{}{"entityType": "person"}}, -32603},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if code := errorCode(call(t, h, session, "prompts/get", tt.params)); code != tt.wantCode {
+                t.Errorf("error code = %d, want %d", code, tt.wantCode)
+            }
+        })
+    }
+}
+
+func TestPromptsGetEmptyGraph(t *testing.T) {
+    h := newTestHandler()
+    session := newReadySession(h)
+
+    r := result(t, call(t, h, session, "prompts/get", map[string]interface{}{
+        "name":      "recall",
+        "arguments": map[string]interface{}{"name": "nobody"},
+    }))
+    message := r["messages"].([]interface{})[0].(map[string]interface{})
+    text := message["content"].(map[string]interface{})["text"].(string)
+
+    // The graph is rendered like a tool result, with empty arrays
+    if strings.Contains(text, "null") || !strings.Contains(text, `"relations": []`) {
+        t.Errorf("prompt text = %q, want the empty graph with empty arrays", text)
+    }
+}
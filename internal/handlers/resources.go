@@ -0,0 +1,273 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "mcp-compose-memory/internal/knowledge"
+    "mcp-compose-memory/internal/models"
+    "net/url"
+    "strings"
+)
+
+// Resource URIs exposing the knowledge graph
+const (
+    graphURI         = "memory://graph"
+    entityURIPrefix  = "memory://entity/"
+    typeURIPrefix    = "memory://type/"
+    resourceMimeType = "application/json"
+
+    // resourceNotFound is the JSON-RPC error code for unknown resource URIs
+    resourceNotFound = -32002
+)
+
+func entityURI(name string) string {
+    return entityURIPrefix + url.PathEscape(name)
+}
+
+// entityResource is the body of a memory://entity/{name} resource
+type entityResource struct {
+    models.Entity
+    Relations []models.Relation `json:"relations"`
+}
+
+func (h *MCPHandler) handleResourcesList(ctx context.Context, request *models.MCPRequest) *models.MCPResponse {
+    cursor, err := listCursor(request)
+    if err != nil {
+        return errorResponse(request.ID, -32602, "Invalid params: "+err.Error())
+    }
+
+    graph, more, err := h.manager.ReadGraphPage(ctx, knowledge.Page{After: cursor.After, Limit: listPageSize})
+    if err != nil {
+        return errorResponse(request.ID, -32603, err.Error())
+    }
+
+    resources := []models.Resource{}
+    if cursor.After == "" {
+        resources = append(resources, models.Resource{
+            URI:         graphURI,
+            Name:        "Knowledge graph",
+            Description: "Every entity and relation in the knowledge graph",
+            MimeType:    resourceMimeType,
+        })
+    }
+    for _, entity := range graph.Entities {
+        resources = append(resources, models.Resource{
+            URI:         entityURI(entity.Name),
+            Name:        entity.Name,
+            Description: "Entity of type " + entity.EntityType + " with its observations and relations",
+            MimeType:    resourceMimeType,
+        })
+    }
+
+    result := map[string]interface{}{"resources": resources}
+    if more {
+        last := graph.Entities[len(graph.Entities)-1]
+        result["nextCursor"] = encodeCursor(pageCursor{After: last.Name})
+    }
+
+    return &models.MCPResponse{
+        ID:      request.ID,
+        JSONRPC: "2.0",
+        Result:  result,
+    }
+}
+
+// resourceTemplates are the parameterized resources the server offers
+var resourceTemplates = []models.ResourceTemplate{
+    {
+        URITemplate: entityURIPrefix + "{name}",
+        Name:        "Entity",
+        Description: "An entity with its observations and every relation that starts or ends at it",
+        MimeType:    resourceMimeType,
+    },
+    {
+        URITemplate: typeURIPrefix + "{entityType}",
+        Name:        "Entities by type",
+        Description: "All entities of one type and the relations among them",
+        MimeType:    resourceMimeType,
+    },
+}
+
+// findResourceTemplate returns the template with the URI template uri, or
+// nil when there is none
+func findResourceTemplate(uri string) *models.ResourceTemplate {
+    for i := range resourceTemplates {
+        if resourceTemplates[i].URITemplate == uri {
+            return &resourceTemplates[i]
+        }
+    }
+    return nil
+}
+
+// templateVariables returns the names of the {variables} in a URI template
+func templateVariables(uriTemplate string) []string {
+    var names []string
+    for {
+        start := strings.IndexByte(uriTemplate, '{')
+        if start < 0 {
+            return names
+        }
+        end := strings.IndexByte(uriTemplate[start:], '}')
+        if end < 0 {
+            return names
+        }
+        names = append(names, uriTemplate[start+1:start+end])
+        uriTemplate = uriTemplate[start+end+1:]
+    }
+}
+
+func (h *MCPHandler) handleResourceTemplatesList(request *models.MCPRequest) *models.MCPResponse {
+    return &models.MCPResponse{
+        ID:      request.ID,
+        JSONRPC: "2.0",
+        Result:  map[string]interface{}{"resourceTemplates": resourceTemplates},
+    }
+}
+
+func (h *MCPHandler) handleResourcesRead(ctx context.Context, request *models.MCPRequest) *models.MCPResponse {
+    paramsBytes, _ := json.Marshal(request.Params)
+    var params models.ResourceParams
+    if err := json.Unmarshal(paramsBytes, &params); err != nil || params.URI == "" {
+        return errorResponse(request.ID, -32602, "Invalid params")
+    }
+
+    body, err := h.readResource(ctx, params.URI)
+    if err != nil {
+        if errors.Is(err, knowledge.ErrNotFound) {
+            return &models.MCPResponse{
+                ID:      request.ID,
+                JSONRPC: "2.0",
+                Error: &models.MCPError{
+                    Code:    resourceNotFound,
+                    Message: "Resource not found",
+                    Data:    map[string]interface{}{"uri": params.URI},
+                },
+            }
+        }
+        return errorResponse(request.ID, -32603, err.Error())
+    }
+
+    text, _ := json.Marshal(body)
+    return &models.MCPResponse{
+        ID:      request.ID,
+        JSONRPC: "2.0",
+        Result: map[string]interface{}{
+            "contents": []models.ResourceContents{{
+                URI:      params.URI,
+                MimeType: resourceMimeType,
+                Text:     string(text),
+            }},
+        },
+    }
+}
+
+// readResource resolves a memory:// URI to the value it names
+func (h *MCPHandler) readResource(ctx context.Context, uri string) (interface{}, error) {
+    switch {
+    case uri == graphURI:
+        return h.manager.ReadGraph(ctx)
+
+    case strings.HasPrefix(uri, entityURIPrefix):
+        name, err := url.PathUnescape(strings.TrimPrefix(uri, entityURIPrefix))
+        if err != nil {
+            return nil, knowledge.ErrNotFound
+        }
+
+        entity, relations, err := h.manager.OpenEntity(ctx, name)
+        if err != nil {
+            return nil, err
+        }
+        return entityResource{Entity: *entity, Relations: relations}, nil
+
+    case strings.HasPrefix(uri, typeURIPrefix):
+        entityType, err := url.PathUnescape(strings.TrimPrefix(uri, typeURIPrefix))
+        if err != nil {
+            return nil, knowledge.ErrNotFound
+        }
+
+        graph, err := h.manager.EntitiesOfType(ctx, entityType)
+        if err != nil {
+            return nil, err
+        }
+        if len(graph.Entities) == 0 {
+            return nil, knowledge.ErrNotFound
+        }
+        return graph, nil
+
+    default:
+        return nil, knowledge.ErrNotFound
+    }
+}
+
+// subscribable reports whether change notifications are sent for uri
+func subscribable(uri string) bool {
+    return uri == graphURI || strings.HasPrefix(uri, entityURIPrefix)
+}
+
+func (h *MCPHandler) handleResourcesSubscribe(session *Session, request *models.MCPRequest) *models.MCPResponse {
+    paramsBytes, _ := json.Marshal(request.Params)
+    var params models.ResourceParams
+    if err := json.Unmarshal(paramsBytes, &params); err != nil || params.URI == "" {
+        return errorResponse(request.ID, -32602, "Invalid params")
+    }
+    if !subscribable(params.URI) {
+        return errorResponse(request.ID, -32602, "Invalid params: subscriptions are supported for "+graphURI+" and "+entityURIPrefix+"{name}")
+    }
+
+    session.subscribe(params.URI)
+
+    return &models.MCPResponse{
+        ID:      request.ID,
+        JSONRPC: "2.0",
+        Result:  map[string]interface{}{},
+    }
+}
+
+func (h *MCPHandler) handleResourcesUnsubscribe(session *Session, request *models.MCPRequest) *models.MCPResponse {
+    paramsBytes, _ := json.Marshal(request.Params)
+    var params models.ResourceParams
+    if err := json.Unmarshal(paramsBytes, &params); err != nil || params.URI == "" {
+        return errorResponse(request.ID, -32602, "Invalid params")
+    }
+
+    session.unsubscribe(params.URI)
+
+    return &models.MCPResponse{
+        ID:      request.ID,
+        JSONRPC: "2.0",
+        Result:  map[string]interface{}{},
+    }
+}
+
+// handleGraphChange turns a knowledge graph change into resource
+// notifications for every session that should hear about it. It runs
+// inside the write that made the change, so the notifications are queued
+// rather than sent.
+func (h *MCPHandler) handleGraphChange(event knowledge.ChangeEvent) {
+    var changed []string
+    for _, names := range [][]string{event.Created, event.Updated, event.Deleted} {
+        for _, name := range names {
+            changed = append(changed, entityURI(name))
+        }
+    }
+    listChanged := len(event.Created) > 0 || len(event.Deleted) > 0
+
+    h.sessions.each(func(session *Session) {
+        if session.State() == stateNew {
+            return
+        }
+
+        if listChanged {
+            session.notifyAsync("notifications/resources/list_changed", nil)
+        }
+        if session.isSubscribed(graphURI) {
+            session.notifyAsync("notifications/resources/updated", map[string]interface{}{"uri": graphURI})
+        }
+        for _, uri := range changed {
+            if session.isSubscribed(uri) {
+                session.notifyAsync("notifications/resources/updated", map[string]interface{}{"uri": uri})
+            }
+        }
+    })
+}
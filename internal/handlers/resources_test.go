@@ -0,0 +1,217 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "mcp-compose-memory/internal/knowledge"
+    "mcp-compose-memory/internal/models"
+    "testing"
+    "time"
+)
+
+// newWriterSession returns a ready session of h whose messages are written
+// to the returned channel, as over stdio
+func newWriterSession(h *MCPHandler) (*Session, chan map[string]interface{}) {
+    messages := make(chan map[string]interface{}, 64)
+
+    session := newSession(newSessionID())
+    session.write = func(message []byte) error {
+        var decoded map[string]interface{}
+        if err := json.Unmarshal(message, &decoded); err != nil {
+            return err
+        }
+        messages <- decoded
+        return nil
+    }
+    session.setState(stateReady)
+    h.sessions.add(session)
+    return session, messages
+}
+
+// notifications collects the notifications that arrive on messages until
+// none has arrived for a while
+func notifications(messages chan map[string]interface{}) []string {
+    var got []string
+    for {
+        select {
+        case message := <-messages:
+            entry := message["method"].(string)
+            if params, ok := message["params"].(map[string]interface{}); ok {
+                entry += " " + params["uri"].(string)
+            }
+            got = append(got, entry)
+        case <-time.After(100 * time.Millisecond):
+            return got
+        }
+    }
+}
+
+func createEntity(t *testing.T, h *MCPHandler, session *Session, name string) {
+    t.Helper()
+
+    response := call(t, h, session, "tools/call", map[string]interface{}{
+        "name": "create_entities",
+        "arguments": map[string]interface{}{"entities": []interface{}{
+            map[string]interface{}{"name": name, "entityType": "person", "observations": []interface{}{}},
+        }},
+    })
+    if r := result(t, response); r["isError"] == true {
+        t.Fatalf("create_entities failed: %v", r)
+    }
+}
+
+func TestResourcesRead(t *testing.T) {
+    h := newTestHandler()
+    session := newReadySession(h)
+    createEntity(t, h, session, "alice")
+    createEntity(t, h, session, "bob smith")
+    call(t, h, session, "tools/call", map[string]interface{}{
+        "name": "create_relations",
+        "arguments": map[string]interface{}{"relations": []interface{}{
+            map[string]interface{}{"from": "alice", "to": "bob smith", "relationType": "knows"},
+        }},
+    })
+
+    tests := []struct {
+        name          string
+        uri           string
+        wantEntities  []string
+        wantRelations int
+    }{
+        {"graph", graphURI, []string{"alice", "bob smith"}, 1},
+        {"entity", entityURI("alice"), []string{"alice"}, 1},
+        {"escaped entity", "memory://entity/bob%20smith", []string{"bob smith"}, 1},
+        {"type", "memory://type/person", []string{"alice", "bob smith"}, 1},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            r := result(t, call(t, h, session, "resources/read", map[string]interface{}{"uri": tt.uri}))
+            contents := r["contents"].([]interface{})[0].(map[string]interface{})
+            if contents["uri"] != tt.uri || contents["mimeType"] != resourceMimeType {
+                t.Errorf("contents uri %v, mimeType %v; want %s, %s", contents["uri"], contents["mimeType"], tt.uri, resourceMimeType)
+            }
+
+            var body struct {
+                Name      string            `json:"name"`
+                Entities  []models.Entity   `json:"entities"`
+                Relations []models.Relation `json:"relations"`
+            }
+            if err := json.Unmarshal([]byte(contents["text"].(string)), &body); err != nil {
+                t.Fatal(err)
+            }
+
+            // An entity resource is the entity itself rather than a graph
+            names := []string{}
+            if body.Name != "" {
+                names = append(names, body.Name)
+            }
+            for _, entity := range body.Entities {
+                names = append(names, entity.Name)
+            }
+            if !equalStrings(names, tt.wantEntities) || len(body.Relations) != tt.wantRelations {
+                t.Errorf("entities %v with %d relations, want %v with %d", names, len(body.Relations), tt.wantEntities, tt.wantRelations)
+            }
+        })
+    }
+
+    for _, uri := range []string{entityURI("nobody"), "memory://type/robot", "memory://elsewhere"} {
+        response := call(t, h, session, "resources/read", map[string]interface{}{"uri": uri})
+        if code := errorCode(response); code != resourceNotFound {
+            t.Errorf("read %s: error code %d, want %d", uri, code, resourceNotFound)
+        }
+    }
+}
+
+func TestResourceTemplatesList(t *testing.T) {
+    h := newTestHandler()
+    r := result(t, call(t, h, newReadySession(h), "resources/templates/list", nil))
+
+    var got []string
+    for _, template := range r["resourceTemplates"].([]interface{}) {
+        got = append(got, template.(map[string]interface{})["uriTemplate"].(string))
+    }
+    if want := []string{"memory://entity/{name}", "memory://type/{entityType}"}; !equalStrings(got, want) {
+        t.Errorf("templates = %v, want %v", got, want)
+    }
+}
+
+func TestResourceNotifications(t *testing.T) {
+    h := NewMCPHandler(knowledge.NewManager(knowledge.NewMemoryStore()))
+    session, messages := newWriterSession(h)
+    defer h.sessions.remove(session.ID)
+
+    for _, uri := range []string{graphURI, entityURI("alice")} {
+        if code := errorCode(call(t, h, session, "resources/subscribe", map[string]interface{}{"uri": uri})); code != 0 {
+            t.Fatalf("subscribe %s: error code %d", uri, code)
+        }
+    }
+
+    createEntity(t, h, session, "alice")
+    want := []string{
+        "notifications/resources/list_changed",
+        "notifications/resources/updated " + graphURI,
+        "notifications/resources/updated " + entityURI("alice"),
+    }
+    if got := notifications(messages); !equalStrings(got, want) {
+        t.Errorf("after create: %v, want %v", got, want)
+    }
+
+    if code := errorCode(call(t, h, session, "resources/unsubscribe", map[string]interface{}{"uri": graphURI})); code != 0 {
+        t.Fatalf("unsubscribe: error code %d", code)
+    }
+
+    call(t, h, session, "tools/call", map[string]interface{}{
+        "name":      "delete_entities",
+        "arguments": map[string]interface{}{"entityNames": []interface{}{"alice"}},
+    })
+    want = []string{
+        "notifications/resources/list_changed",
+        "notifications/resources/updated " + entityURI("alice"),
+    }
+    if got := notifications(messages); !equalStrings(got, want) {
+        t.Errorf("after delete: %v, want %v", got, want)
+    }
+}
+
+func TestResourceNotificationsSlowClient(t *testing.T) {
+    h := NewMCPHandler(knowledge.NewManager(knowledge.NewMemoryStore()))
+
+    // A client that never reads blocks every write to it
+    unblock := make(chan struct{})
+    defer close(unblock)
+    session := newSession(newSessionID())
+    session.write = func([]byte) error {
+        <-unblock
+        return nil
+    }
+    session.setState(stateReady)
+    h.sessions.add(session)
+    defer h.sessions.remove(session.ID)
+
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        for _, name := range []string{"alice", "bob"} {
+            h.HandleMessage(context.Background(), session, []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"create_entities","arguments":{"entities":[{"name":"`+name+`","entityType":"person","observations":[]}]}}}`))
+        }
+    }()
+
+    select {
+    case <-done:
+    case <-time.After(5 * time.Second):
+        t.Fatal("create_entities waited for a blocked client")
+    }
+}
+
+func equalStrings(a, b []string) bool {
+    if len(a) != len(b) {
+        return false
+    }
+    for i := range a {
+        if a[i] != b[i] {
+            return false
+        }
+    }
+    return true
+}
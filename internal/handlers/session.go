@@ -0,0 +1,426 @@
+package handlers
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "log"
+    "mcp-compose-memory/internal/knowledge"
+    "mcp-compose-memory/internal/models"
+    "strconv"
+    "sync"
+    "time"
+)
+
+const (
+    // sessionIdleTimeout is how long an HTTP session may go unused before
+    // it is discarded
+    sessionIdleTimeout = time.Hour
+
+    // sessionSweepInterval is how often idle HTTP sessions are looked for
+    sessionSweepInterval = time.Minute
+
+    // maxSessionHistory bounds the events kept for Last-Event-ID replay
+    maxSessionHistory = 256
+
+    // maxQueuedNotifications bounds the notifications waiting for a slow
+    // client in a session's outbox
+    maxQueuedNotifications = 256
+)
+
+// sessionState tracks a session through the MCP initialization handshake
+type sessionState int
+
+const (
+    // stateNew sessions accept only initialize and ping
+    stateNew sessionState = iota
+    // stateInitializing sessions have answered initialize and await the
+    // client's notifications/initialized
+    stateInitializing
+    // stateReady sessions have completed the handshake
+    stateReady
+)
+
+// sseEvent is a server-to-client message queued for the standalone stream
+type sseEvent struct {
+    ID   string
+    Data []byte
+}
+
+// Session is the server-side state of one MCP client connection. Over HTTP
+// it is identified by the Mcp-Session-Id header; over stdio the process has
+// exactly one session.
+type Session struct {
+    ID string
+
+    mu          sync.Mutex
+    state       sessionState
+    lastSeen    time.Time
+    nextEventID int
+    history     []sseEvent
+    stream      chan sseEvent
+    closed      chan struct{}
+
+    // subscriptions holds the resource URIs the client subscribed to
+    subscriptions map[string]bool
+
+    // logLevel is the least severe log record sent to the client
+    logLevel knowledge.LogLevel
+
+    // inflight cancels the requests being handled, keyed by requestKey
+    inflight map[string]context.CancelCauseFunc
+
+    // write delivers messages directly on transports with a single duplex
+    // stream, such as stdio. HTTP sessions queue them for the GET stream.
+    write func(message []byte) error
+
+    // outbox holds the notifications sent by notifyAsync until the
+    // delivering goroutine, started on first use, sends them
+    outbox      chan []byte
+    deliverOnce sync.Once
+}
+
+func newSession(id string) *Session {
+    return &Session{
+        ID:            id,
+        lastSeen:      time.Now(),
+        closed:        make(chan struct{}),
+        subscriptions: make(map[string]bool),
+        logLevel:      defaultLogLevel,
+        inflight:      make(map[string]context.CancelCauseFunc),
+        outbox:        make(chan []byte, maxQueuedNotifications),
+    }
+}
+
+func newSessionID() string {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        panic(err)
+    }
+    return hex.EncodeToString(b)
+}
+
+// Notify sends a JSON-RPC notification to the client
+func (s *Session) Notify(method string, params interface{}) {
+    message, err := encodeNotification(method, params)
+    if err != nil {
+        return
+    }
+    s.send(message)
+}
+
+// notifyAsync queues a notification to be sent in the background, so that
+// the caller never waits on a slow client. Queued notifications keep their
+// order; those that would overflow the queue are dropped.
+func (s *Session) notifyAsync(method string, params interface{}) {
+    message, err := encodeNotification(method, params)
+    if err != nil {
+        return
+    }
+
+    s.deliverOnce.Do(func() { go s.deliver() })
+
+    select {
+    case s.outbox <- message:
+    default:
+        log.Printf("Dropping %s for slow session %s", method, s.ID)
+    }
+}
+
+// deliver sends the notifications queued by notifyAsync until the session
+// is closed
+func (s *Session) deliver() {
+    for {
+        select {
+        case message := <-s.outbox:
+            s.send(message)
+        case <-s.closed:
+            return
+        }
+    }
+}
+
+// notifyRequest sends a notification about the request ctx belongs to. It
+// goes to the request's own response stream if the transport opened one.
+func (s *Session) notifyRequest(ctx context.Context, method string, params interface{}) {
+    send, ok := ctx.Value(requestStreamKey{}).(func([]byte) error)
+    if !ok {
+        s.Notify(method, params)
+        return
+    }
+
+    message, err := encodeNotification(method, params)
+    if err != nil {
+        return
+    }
+    if err := send(message); err != nil {
+        log.Printf("Failed to send %s to session %s: %v", method, s.ID, err)
+    }
+}
+
+func encodeNotification(method string, params interface{}) ([]byte, error) {
+    message, err := json.Marshal(models.MCPNotification{
+        JSONRPC: "2.0",
+        Method:  method,
+        Params:  params,
+    })
+    if err != nil {
+        log.Printf("Failed to encode %s notification: %v", method, err)
+    }
+    return message, err
+}
+
+type requestStreamKey struct{}
+
+// withRequestStream returns a context whose request notifications are
+// delivered by send, such as on the SSE stream answering an HTTP POST
+func withRequestStream(ctx context.Context, send func([]byte) error) context.Context {
+    return context.WithValue(ctx, requestStreamKey{}, send)
+}
+
+func (s *Session) send(message []byte) {
+    if s.write != nil {
+        if err := s.write(message); err != nil {
+            log.Printf("Failed to send message to session %s: %v", s.ID, err)
+        }
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.nextEventID++
+    event := sseEvent{ID: strconv.Itoa(s.nextEventID), Data: message}
+
+    s.history = append(s.history, event)
+    if len(s.history) > maxSessionHistory {
+        s.history = s.history[len(s.history)-maxSessionHistory:]
+    }
+
+    if s.stream != nil {
+        select {
+        case s.stream <- event:
+        default:
+            // The client is not keeping up; it can catch up from history
+            // by reconnecting with Last-Event-ID.
+            log.Printf("Dropping event %s for slow session %s", event.ID, s.ID)
+        }
+    }
+}
+
+// attachStream registers a new standalone SSE stream for the session,
+// replacing any existing one, and returns the events to replay after
+// lastEventID. The returned channel is closed when the stream is replaced.
+func (s *Session) attachStream(lastEventID string) (chan sseEvent, []sseEvent) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.stream != nil {
+        close(s.stream)
+    }
+    s.stream = make(chan sseEvent, 64)
+
+    var replay []sseEvent
+    if lastEventID != "" {
+        for i, event := range s.history {
+            if event.ID == lastEventID {
+                replay = append(replay, s.history[i+1:]...)
+                break
+            }
+        }
+    }
+
+    return s.stream, replay
+}
+
+func (s *Session) detachStream(stream chan sseEvent) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.stream == stream {
+        close(s.stream)
+        s.stream = nil
+    }
+}
+
+// State returns the session's position in the initialization handshake
+func (s *Session) State() sessionState {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.state
+}
+
+func (s *Session) setState(state sessionState) {
+    s.mu.Lock()
+    s.state = state
+    s.mu.Unlock()
+}
+
+// LogLevel returns the least severe level of log records sent to the client
+func (s *Session) LogLevel() knowledge.LogLevel {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.logLevel
+}
+
+func (s *Session) setLogLevel(level knowledge.LogLevel) {
+    s.mu.Lock()
+    s.logLevel = level
+    s.mu.Unlock()
+}
+
+func (s *Session) subscribe(uri string) {
+    s.mu.Lock()
+    s.subscriptions[uri] = true
+    s.mu.Unlock()
+}
+
+func (s *Session) unsubscribe(uri string) {
+    s.mu.Lock()
+    delete(s.subscriptions, uri)
+    s.mu.Unlock()
+}
+
+func (s *Session) isSubscribed(uri string) bool {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.subscriptions[uri]
+}
+
+// requestKey identifies a request by its JSON-RPC id, which may be a
+// string or a number
+func requestKey(id interface{}) string {
+    key, _ := json.Marshal(id)
+    return string(key)
+}
+
+// track makes a request cancellable until the returned func is called
+func (s *Session) track(id interface{}, cancel context.CancelCauseFunc) func() {
+    key := requestKey(id)
+
+    s.mu.Lock()
+    s.inflight[key] = cancel
+    s.mu.Unlock()
+
+    return func() {
+        s.mu.Lock()
+        delete(s.inflight, key)
+        s.mu.Unlock()
+    }
+}
+
+// cancelRequest cancels a request in flight and reports whether there was one
+func (s *Session) cancelRequest(id interface{}) bool {
+    s.mu.Lock()
+    cancel, ok := s.inflight[requestKey(id)]
+    s.mu.Unlock()
+
+    if ok {
+        cancel(errRequestCancelled)
+    }
+    return ok
+}
+
+func (s *Session) touch() {
+    s.mu.Lock()
+    s.lastSeen = time.Now()
+    s.mu.Unlock()
+}
+
+func (s *Session) idleSince() time.Time {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.lastSeen
+}
+
+// sessionStore tracks the active HTTP sessions
+type sessionStore struct {
+    mu       sync.Mutex
+    sessions map[string]*Session
+}
+
+func newSessionStore() *sessionStore {
+    return &sessionStore{sessions: make(map[string]*Session)}
+}
+
+func (st *sessionStore) create() *Session {
+    st.mu.Lock()
+    defer st.mu.Unlock()
+
+    session := newSession(newSessionID())
+    st.sessions[session.ID] = session
+    return session
+}
+
+// expire drops the HTTP sessions unused since before idleBefore, whose
+// clients went away without a DELETE, and returns how many it dropped
+func (st *sessionStore) expire(idleBefore time.Time) int {
+    st.mu.Lock()
+    defer st.mu.Unlock()
+
+    expired := 0
+    for id, session := range st.sessions {
+        if session.write == nil && session.idleSince().Before(idleBefore) {
+            close(session.closed)
+            delete(st.sessions, id)
+            expired++
+        }
+    }
+    return expired
+}
+
+// add registers a session created by another transport, such as stdio,
+// so that it receives server-initiated notifications
+func (st *sessionStore) add(session *Session) {
+    st.mu.Lock()
+    st.sessions[session.ID] = session
+    st.mu.Unlock()
+}
+
+// each calls fn for every active session
+func (st *sessionStore) each(fn func(*Session)) {
+    st.mu.Lock()
+    sessions := make([]*Session, 0, len(st.sessions))
+    for _, session := range st.sessions {
+        sessions = append(sessions, session)
+    }
+    st.mu.Unlock()
+
+    for _, session := range sessions {
+        fn(session)
+    }
+}
+
+func (st *sessionStore) get(id string) *Session {
+    st.mu.Lock()
+    defer st.mu.Unlock()
+
+    session := st.sessions[id]
+    if session != nil {
+        session.touch()
+    }
+    return session
+}
+
+func (st *sessionStore) remove(id string) bool {
+    st.mu.Lock()
+    defer st.mu.Unlock()
+
+    session, ok := st.sessions[id]
+    if ok {
+        close(session.closed)
+        delete(st.sessions, id)
+    }
+    return ok
+}
+
+func (st *sessionStore) closeAll() {
+    st.mu.Lock()
+    defer st.mu.Unlock()
+
+    for id, session := range st.sessions {
+        close(session.closed)
+        delete(st.sessions, id)
+    }
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/json"
+    "io"
+    "sync"
+)
+
+const (
+    // maxStdioMessageSize bounds a single newline-delimited JSON-RPC message
+    maxStdioMessageSize = 64 * 1024 * 1024
+
+    // stdioQueueSize bounds the messages read ahead of the one being handled
+    stdioQueueSize = 64
+)
+
+// ServeStdio reads newline-delimited JSON-RPC messages from in and writes
+// one response per line to out, until in is closed, ctx is done or a write
+// to out fails. Requests run under ctx, so cancelling it aborts the request
+// being handled; ServeStdio returns only once no request is in progress.
+// Nothing but protocol messages may be written to out, so callers must
+// send logs elsewhere.
+func (h *MCPHandler) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+    var mu sync.Mutex
+    writer := bufio.NewWriter(out)
+    write := func(message []byte) error {
+        mu.Lock()
+        defer mu.Unlock()
+
+        if _, err := writer.Write(append(message, '\n')); err != nil {
+            return err
+        }
+        return writer.Flush()
+    }
+
+    // A stdio connection is a single session for the life of the process
+    session := newSession(newSessionID())
+    session.write = write
+    h.sessions.add(session)
+    defer h.sessions.remove(session.ID)
+
+    // The reader runs ahead of the message being handled, so that a
+    // cancellation reaches the request it cancels. If handling stops
+    // first, the reader is abandoned, blocked on in until the caller
+    // closes it or exits.
+    queue := make(chan stdioMessage, stdioQueueSize)
+    stop := make(chan struct{})
+    defer close(stop)
+    readErr := make(chan error, 1)
+    go func() {
+        defer close(queue)
+        readErr <- h.readStdio(in, session, queue, stop)
+    }()
+
+    for {
+        select {
+        case message, ok := <-queue:
+            if !ok {
+                return <-readErr
+            }
+
+            reply := message.reply
+            if reply == nil {
+                reply = h.handleMessages(ctx, session, message.messages, message.batch)
+            }
+            if reply == nil {
+                continue
+            }
+
+            encoded, err := json.Marshal(reply)
+            if err == nil {
+                err = write(encoded)
+            }
+            if err != nil {
+                return err
+            }
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    }
+}
+
+// readStdio queues the messages read from in until in is closed or stop is
+// closed. Cancellations are handled at once rather than queued.
+func (h *MCPHandler) readStdio(in io.Reader, session *Session, queue chan<- stdioMessage, stop <-chan struct{}) error {
+    scanner := bufio.NewScanner(in)
+    scanner.Buffer(make([]byte, 0, 64*1024), maxStdioMessageSize)
+
+    for scanner.Scan() {
+        line := bytes.TrimSpace(scanner.Bytes())
+        if len(line) == 0 {
+            continue
+        }
+
+        var message stdioMessage
+        messages, batch, errResponse := parseMessages(line)
+        switch {
+        case errResponse != nil:
+            message = stdioMessage{reply: errResponse}
+        case !batch && messages[0].notification && messages[0].request.Method == "notifications/cancelled":
+            h.handleNotification(session, messages[0].request)
+            continue
+        default:
+            message = stdioMessage{messages: messages, batch: batch}
+        }
+
+        select {
+        case queue <- message:
+        case <-stop:
+            return nil
+        }
+    }
+    return scanner.Err()
+}
+
+// stdioMessage is a message queued for the stdio worker: either decoded
+// messages to handle or a reply that is already known
+type stdioMessage struct {
+    messages []incomingMessage
+    batch    bool
+    reply    interface{}
+}
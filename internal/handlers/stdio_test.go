@@ -0,0 +1,162 @@
+package handlers
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "errors"
+    "io"
+    "testing"
+    "time"
+)
+
+// stdioClient drives ServeStdio over pipes, as a client process would
+type stdioClient struct {
+    t      *testing.T
+    in     *io.PipeWriter
+    out    *bufio.Reader
+    served chan error
+}
+
+func startStdio(t *testing.T, ctx context.Context, h *MCPHandler) *stdioClient {
+    inR, inW := io.Pipe()
+    outR, outW := io.Pipe()
+
+    c := &stdioClient{t: t, in: inW, out: bufio.NewReader(outR), served: make(chan error, 1)}
+    go func() {
+        err := h.ServeStdio(ctx, inR, outW)
+        outW.Close()
+        c.served <- err
+    }()
+    t.Cleanup(func() { inW.Close() })
+    return c
+}
+
+func (c *stdioClient) send(line string) {
+    c.t.Helper()
+    if _, err := io.WriteString(c.in, line+"\n"); err != nil {
+        c.t.Fatal(err)
+    }
+}
+
+// nextResponse reads lines until a response, skipping notifications
+func (c *stdioClient) nextResponse() map[string]interface{} {
+    c.t.Helper()
+
+    for {
+        line, err := c.out.ReadBytes('\n')
+        if err != nil {
+            c.t.Fatalf("reading response: %v", err)
+        }
+        var message map[string]interface{}
+        if err := json.Unmarshal(line, &message); err != nil {
+            c.t.Fatalf("line %q is not JSON: %v", line, err)
+        }
+        if _, ok := message["method"]; !ok {
+            return message
+        }
+    }
+}
+
+// response reads the next response, which must answer the request with id
+func (c *stdioClient) response(id float64) map[string]interface{} {
+    c.t.Helper()
+
+    message := c.nextResponse()
+    if message["id"] != id {
+        c.t.Fatalf("response %v, want the response to %v", message, id)
+    }
+    return message
+}
+
+// wait returns the error ServeStdio returned
+func (c *stdioClient) wait() error {
+    c.t.Helper()
+
+    select {
+    case err := <-c.served:
+        return err
+    case <-time.After(5 * time.Second):
+        c.t.Fatal("ServeStdio did not return")
+    }
+    return nil
+}
+
+func TestServeStdio(t *testing.T) {
+    c := startStdio(t, context.Background(), newTestHandler())
+
+    c.send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`)
+    if r := result(t, c.response(1)); r["protocolVersion"] != "2025-03-26" {
+        t.Errorf("initialize result = %v", r)
+    }
+
+    // The notification gets no response, so the next line answers the call
+    c.send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+    c.send(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"create_entities","arguments":{"entities":[{"name":"alice","entityType":"person","observations":[]}]}}}`)
+    if r := result(t, c.response(2)); r["isError"] == true {
+        t.Errorf("create_entities failed: %v", r)
+    }
+
+    c.send(`not json`)
+    if response := c.nextResponse(); errorCode(response) != -32700 || response["id"] != nil {
+        t.Errorf("response to a malformed line = %v, want a parse error with a null id", response)
+    }
+}
+
+func TestServeStdioEndsWithInput(t *testing.T) {
+    c := startStdio(t, context.Background(), newTestHandler())
+
+    c.send(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+    c.response(1)
+    c.in.Close()
+
+    if err := c.wait(); err != nil {
+        t.Errorf("ServeStdio = %v, want nil at the end of input", err)
+    }
+}
+
+// failingWriter fails every write, as a closed stdout would
+type failingWriter struct{}
+
+var errClosedOutput = errors.New("broken pipe")
+
+func (failingWriter) Write([]byte) (int, error) {
+    return 0, errClosedOutput
+}
+
+func TestServeStdioWriteFailure(t *testing.T) {
+    inR, inW := io.Pipe()
+    defer inW.Close()
+
+    served := make(chan error, 1)
+    go func() {
+        served <- newTestHandler().ServeStdio(context.Background(), inR, failingWriter{})
+    }()
+
+    // The input stays open, so ServeStdio must stop on the write failure
+    // rather than wait for more input
+    if _, err := io.WriteString(inW, `{"jsonrpc":"2.0","id":1,"method":"ping"}`+"\n"); err != nil {
+        t.Fatal(err)
+    }
+    select {
+    case err := <-served:
+        if !errors.Is(err, errClosedOutput) {
+            t.Errorf("ServeStdio = %v, want %v", err, errClosedOutput)
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatal("ServeStdio kept reading after a write failed")
+    }
+}
+
+func TestServeStdioCancelled(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    c := startStdio(t, ctx, newTestHandler())
+
+    c.send(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+    c.response(1)
+    cancel()
+
+    if err := c.wait(); !errors.Is(err, context.Canceled) {
+        t.Errorf("ServeStdio = %v, want context.Canceled", err)
+    }
+}
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "log"
+    "mcp-compose-memory/internal/models"
+    "mcp-compose-memory/internal/schema"
+    "time"
+)
+
+// toolHandler runs a tool on its raw call arguments
+type toolHandler func(ctx context.Context, args map[string]interface{}) (interface{}, error)
+
+type tool struct {
+    definition models.Tool
+    handler    toolHandler
+
+    // timeout overrides the registry's default timeout when positive
+    timeout time.Duration
+}
+
+// Validator is implemented by tool inputs that check their own arguments
+// after decoding. A Validate error is reported as invalid params.
+type Validator interface {
+    Validate() error
+}
+
+// TextResult is implemented by tool results whose text content differs
+// from their JSON encoding, such as results that older clients expect in
+// an earlier form.
+type TextResult interface {
+    Text() string
+}
+
+// AddTool registers a tool on h, replacing any tool with the same name.
+// The call arguments are checked against the input schema, decoded into
+// In, checked with Validate if In is a Validator, and passed to handler
+// with a context that is cancelled if the client cancels the call or its
+// timeout expires. The Out it returns is sent as the structured content of
+// the result and, encoded as JSON or by its Text method, as the text
+// content. The input and output schemas are derived
+// from In and Out unless set on tool. A handler returning
+// models.ToolResponse builds the whole result itself and has no output
+// schema.
+//
+// Tools must be added before the handler starts serving requests.
+func AddTool[In, Out any](h *MCPHandler, tool models.Tool, handler func(ctx context.Context, input In) (Out, error)) {
+    var zeroIn In
+    var zeroOut Out
+
+    if tool.InputSchema == nil {
+        tool.InputSchema = schema.ForInput(zeroIn)
+    }
+    if _, raw := interface{}(zeroOut).(models.ToolResponse); !raw && tool.OutputSchema == nil {
+        tool.OutputSchema = schema.For(zeroOut)
+    }
+
+    inputSchema := tool.InputSchema
+    h.tools.add(tool, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+        if args == nil {
+            args = map[string]interface{}{}
+        }
+        if err := schema.Validate(inputSchema, args); err != nil {
+            return nil, &argumentsError{err: err}
+        }
+
+        var input In
+        if err := decodeArguments(args, &input); err != nil {
+            return nil, err
+        }
+        if v, ok := interface{}(&input).(Validator); ok {
+            if err := v.Validate(); err != nil {
+                return nil, &argumentsError{err: err}
+            }
+        }
+
+        out, err := handler(ctx, input)
+        if err != nil {
+            return nil, err
+        }
+        return toolResponse(out), nil
+    })
+}
+
+// toolResponse wraps the result of a typed tool handler
+func toolResponse(out interface{}) models.ToolResponse {
+    switch out := out.(type) {
+    case models.ToolResponse:
+        return out
+    case TextResult:
+        return models.ToolResponse{
+            Content:           []models.ToolContent{{Type: "text", Text: out.Text()}},
+            StructuredContent: out,
+        }
+    default:
+        resultBytes, _ := json.Marshal(out)
+        return models.ToolResponse{
+            Content:           []models.ToolContent{{Type: "text", Text: string(resultBytes)}},
+            StructuredContent: out,
+        }
+    }
+}
+
+// toolRegistry holds the tools offered by tools/list and tools/call, in the
+// order they are listed
+type toolRegistry struct {
+    tools  []*tool
+    byName map[string]*tool
+
+    // defaultTimeout bounds calls to tools without their own timeout; zero
+    // means no limit
+    defaultTimeout time.Duration
+}
+
+func newToolRegistry() *toolRegistry {
+    return &toolRegistry{byName: make(map[string]*tool)}
+}
+
+// add registers a tool, replacing any tool with the same name
+func (r *toolRegistry) add(definition models.Tool, handler toolHandler) {
+    t := &tool{definition: definition, handler: handler}
+    if _, ok := r.byName[definition.Name]; ok {
+        log.Printf("Tool %s replaces an existing tool", definition.Name)
+        for i, existing := range r.tools {
+            if existing.definition.Name == definition.Name {
+                r.tools[i] = t
+            }
+        }
+    } else {
+        r.tools = append(r.tools, t)
+    }
+    r.byName[definition.Name] = t
+}
+
+func (r *toolRegistry) get(name string) *tool {
+    return r.byName[name]
+}
+
+// timeout returns how long a call to t may run, or zero for no limit
+func (r *toolRegistry) timeout(t *tool) time.Duration {
+    if t.timeout > 0 {
+        return t.timeout
+    }
+    return r.defaultTimeout
+}
+
+// callsTimeout returns how long the tools/call requests among messages may
+// run one after another, or zero if none of them is limited
+func (r *toolRegistry) callsTimeout(messages []incomingMessage) time.Duration {
+    var total time.Duration
+    for _, message := range messages {
+        if message.request == nil || message.request.Method != "tools/call" {
+            continue
+        }
+        params, ok := message.request.Params.(map[string]interface{})
+        if !ok {
+            continue
+        }
+        name, _ := params["name"].(string)
+        if t := r.get(name); t != nil {
+            total += r.timeout(t)
+        }
+    }
+    return total
+}
+
+func (r *toolRegistry) definitions() []models.Tool {
+    definitions := make([]models.Tool, len(r.tools))
+    for i, t := range r.tools {
+        definitions[i] = t.definition
+    }
+    return definitions
+}
+
+// SetDefaultToolTimeout limits how long a tool call may run unless the tool
+// has its own timeout. A call that runs out of time is cancelled and
+// reported to the client as a tool error. Zero removes the limit.
+func (h *MCPHandler) SetDefaultToolTimeout(timeout time.Duration) {
+    h.tools.defaultTimeout = timeout
+}
+
+// SetToolTimeout limits how long a call to the named tool may run,
+// overriding the default timeout. Zero reverts the tool to the default.
+func (h *MCPHandler) SetToolTimeout(name string, timeout time.Duration) error {
+    t := h.tools.get(name)
+    if t == nil {
+        return fmt.Errorf("unknown tool: %s", name)
+    }
+    t.timeout = timeout
+    return nil
+}
+
+func hint(value bool) *bool {
+    return &value
+}
+
+// Annotations shared by the built-in tools. The knowledge graph is a closed
+// domain, so no tool interacts with an open world.
+var (
+    readOnlyAnnotations = &models.ToolAnnotations{
+        ReadOnlyHint:  hint(true),
+        OpenWorldHint: hint(false),
+    }
+    // Writes that skip entities, relations, and observations that already
+    // exist, so repeating them has no further effect
+    additiveAnnotations = &models.ToolAnnotations{
+        ReadOnlyHint:    hint(false),
+        DestructiveHint: hint(false),
+        IdempotentHint:  hint(true),
+        OpenWorldHint:   hint(false),
+    }
+    destructiveAnnotations = &models.ToolAnnotations{
+        ReadOnlyHint:    hint(false),
+        DestructiveHint: hint(true),
+        IdempotentHint:  hint(true),
+        OpenWorldHint:   hint(false),
+    }
+)
+
+// registerTools adds the knowledge graph tools
+func (h *MCPHandler) registerTools() {
+    AddTool(h, models.Tool{
+        Name:        "create_entities",
+        Description: "Create multiple new entities in the knowledge graph",
+        Annotations: additiveAnnotations,
+    }, h.handleCreateEntities)
+
+    AddTool(h, models.Tool{
+        Name:        "create_relations",
+        Description: "Create multiple new relations between entities in the knowledge graph. Relations should be in active voice",
+        Annotations: additiveAnnotations,
+    }, h.handleCreateRelations)
+
+    AddTool(h, models.Tool{
+        Name:        "add_observations",
+        Description: "Add new observations to existing entities in the knowledge graph",
+        Annotations: additiveAnnotations,
+    }, h.handleAddObservations)
+
+    AddTool(h, models.Tool{
+        Name:        "delete_entities",
+        Description: "Delete multiple entities and their associated relations from the knowledge graph",
+        Annotations: destructiveAnnotations,
+    }, h.handleDeleteEntities)
+
+    AddTool(h, models.Tool{
+        Name:        "delete_observations",
+        Description: "Delete specific observations from entities in the knowledge graph",
+        Annotations: destructiveAnnotations,
+    }, h.handleDeleteObservations)
+
+    AddTool(h, models.Tool{
+        Name:        "delete_relations",
+        Description: "Delete multiple relations from the knowledge graph",
+        Annotations: destructiveAnnotations,
+    }, h.handleDeleteRelations)
+
+    AddTool(h, models.Tool{
+        Name:        "read_graph",
+        Description: "Read the entire knowledge graph. Pass limit or cursor to read it in pages ordered by entity name; each page holds the relations from its entities and a nextCursor until the last page.",
+        Annotations: readOnlyAnnotations,
+    }, h.handleReadGraph)
+
+    AddTool(h, models.Tool{
+        Name:        "search_nodes",
+        Description: "Search for nodes in the knowledge graph based on a query. Pass limit or cursor to page through the matches ordered by entity name.",
+        Annotations: readOnlyAnnotations,
+    }, h.handleSearchNodes)
+
+    AddTool(h, models.Tool{
+        Name:        "open_nodes",
+        Description: "Open specific nodes in the knowledge graph by their names",
+        Annotations: readOnlyAnnotations,
+    }, h.handleOpenNodes)
+
+    AddTool(h, models.Tool{
+        Name:        "get_neighborhood",
+        Description: "Get the entities within a number of relation hops of the named entities, the relations among them, and how many hops away each entity is. Use this to learn what an entity is connected to.",
+        Annotations: readOnlyAnnotations,
+    }, h.handleGetNeighborhood)
+
+    AddTool(h, models.Tool{
+        Name:        "find_paths",
+        Description: "Find how two entities are connected: the shortest chains of relations from one to the other and, with allPaths, every chain up to maxLength relations that visits no entity twice.",
+        Annotations: readOnlyAnnotations,
+    }, h.handleFindPaths)
+
+    AddTool(h, models.Tool{
+        Name:        "graph_stats",
+        Description: "Summarize the structure of the knowledge graph: the most central entities by PageRank with their relation counts, the groups of connected entities, and the entities without relations.",
+        Annotations: readOnlyAnnotations,
+    }, h.handleGraphStats)
+
+    AddTool(h, models.Tool{
+        Name:        "detect_communities",
+        Description: "Group related entities into clusters, such as the people and decisions of one project, and describe each cluster by its members and most frequent entity and relation types.",
+        Annotations: readOnlyAnnotations,
+    }, h.handleDetectCommunities)
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "mcp-compose-memory/internal/models"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+// listedTools returns the tools/list definitions of h by name
+func listedTools(t *testing.T, h *MCPHandler) map[string]map[string]interface{} {
+    t.Helper()
+
+    r := result(t, call(t, h, newReadySession(h), "tools/list", nil))
+    tools := make(map[string]map[string]interface{})
+    for _, tool := range r["tools"].([]interface{}) {
+        definition := tool.(map[string]interface{})
+        tools[definition["name"].(string)] = definition
+    }
+    return tools
+}
+
+func TestToolsOutputSchemas(t *testing.T) {
+    for name, tool := range listedTools(t, newTestHandler()) {
+        outputSchema, ok := tool["outputSchema"].(map[string]interface{})
+        if !ok || outputSchema["type"] != "object" {
+            t.Errorf("%s: outputSchema = %v, want an object schema", name, tool["outputSchema"])
+        }
+    }
+}
+
+func TestToolsAnnotations(t *testing.T) {
+    tests := []struct {
+        tools           []string
+        wantReadOnly    bool
+        wantDestructive bool
+        wantIdempotent  bool
+    }{
+        {[]string{"read_graph", "search_nodes", "open_nodes"}, true, false, false},
+        {[]string{"delete_entities", "delete_observations", "delete_relations"}, false, true, true},
+        {[]string{"create_entities", "create_relations", "add_observations"}, false, false, true},
+    }
+
+    tools := listedTools(t, newTestHandler())
+    for _, tt := range tests {
+        for _, name := range tt.tools {
+            annotations := tools[name]["annotations"].(map[string]interface{})
+            readOnly, _ := annotations["readOnlyHint"].(bool)
+            destructive, _ := annotations["destructiveHint"].(bool)
+            idempotent, _ := annotations["idempotentHint"].(bool)
+            if readOnly != tt.wantReadOnly || destructive != tt.wantDestructive || idempotent != tt.wantIdempotent {
+                t.Errorf("%s: annotations = %v", name, annotations)
+            }
+            if annotations["openWorldHint"] != false {
+                t.Errorf("%s: openWorldHint = %v, want false", name, annotations["openWorldHint"])
+            }
+        }
+    }
+}
+
+func TestToolsInputSchemas(t *testing.T) {
+    h := newTestHandler()
+
+    // The schema of create_entities is generated from models.CreateEntitiesInput
+    inputSchema := listedTools(t, h)["create_entities"]["inputSchema"].(map[string]interface{})
+    entities := inputSchema["properties"].(map[string]interface{})["entities"].(map[string]interface{})
+    items := entities["items"].(map[string]interface{})
+    if !reflect.DeepEqual(inputSchema["required"], []interface{}{"entities"}) {
+        t.Errorf("required = %v, want [entities]", inputSchema["required"])
+    }
+    if !reflect.DeepEqual(items["required"], []interface{}{"name", "entityType", "observations"}) {
+        t.Errorf("entity required = %v, want [name entityType observations]", items["required"])
+    }
+    if items["additionalProperties"] != false || entities["maxItems"] != float64(1000) {
+        t.Errorf("entities schema = %v, want closed items and at most 1000", entities)
+    }
+
+    // Calls are checked against the same schema
+    session := newReadySession(h)
+    for name, entity := range map[string]map[string]interface{}{
+        "missing field": {"name": "alice", "observations": []interface{}{}},
+        "extra field":   {"name": "alice", "entityType": "person", "observations": []interface{}{}, "age": 30},
+        "empty name":    {"name": "", "entityType": "person", "observations": []interface{}{}},
+    } {
+        response := call(t, h, session, "tools/call", map[string]interface{}{
+            "name":      "create_entities",
+            "arguments": map[string]interface{}{"entities": []interface{}{entity}},
+        })
+        if code := errorCode(response); code != -32602 {
+            t.Errorf("%s: error code = %d, want -32602", name, code)
+        }
+    }
+}
+
+func TestToolsStructuredContent(t *testing.T) {
+    h := newTestHandler()
+    session := newReadySession(h)
+
+    // Each call runs after the ones before it. The text block holds the
+    // structured content, or for the write tools the bare array under
+    // textKey that earlier versions returned.
+    tests := []struct {
+        tool      string
+        arguments map[string]interface{}
+        textKey   string
+        wantCount int
+    }{
+        {"create_entities", map[string]interface{}{"entities": []interface{}{
+            map[string]interface{}{"name": "alice", "entityType": "person", "observations": []interface{}{"likes tea"}},
+            map[string]interface{}{"name": "bob", "entityType": "person", "observations": []interface{}{}},
+        }}, "entities", 2},
+        {"create_relations", map[string]interface{}{"relations": []interface{}{
+            map[string]interface{}{"from": "alice", "to": "bob", "relationType": "knows"},
+            map[string]interface{}{"from": "alice", "to": "nobody", "relationType": "knows"},
+        }}, "relations", 1},
+        {"add_observations", map[string]interface{}{"observations": []interface{}{
+            map[string]interface{}{"entityName": "bob", "contents": []interface{}{"likes coffee"}},
+        }}, "results", 1},
+        {"read_graph", map[string]interface{}{}, "", 2},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.tool, func(t *testing.T) {
+            r := result(t, call(t, h, session, "tools/call", map[string]interface{}{"name": tt.tool, "arguments": tt.arguments}))
+            if r["isError"] == true {
+                t.Fatalf("%s failed: %v", tt.tool, r)
+            }
+            structured, ok := r["structuredContent"].(map[string]interface{})
+            if !ok {
+                t.Fatalf("structuredContent = %v, want an object", r["structuredContent"])
+            }
+
+            var want interface{} = structured
+            if tt.textKey != "" {
+                want = structured[tt.textKey]
+            }
+            var text interface{}
+            content := r["content"].([]interface{})[0].(map[string]interface{})
+            if err := json.Unmarshal([]byte(content["text"].(string)), &text); err != nil {
+                t.Fatalf("text is not JSON: %v", err)
+            }
+            if !reflect.DeepEqual(text, want) {
+                t.Errorf("text = %v, want %v", text, want)
+            }
+
+            key := tt.textKey
+            if key == "" {
+                key = "entities"
+            }
+            if got := len(structured[key].([]interface{})); got != tt.wantCount {
+                t.Errorf("%d %s, want %d", got, key, tt.wantCount)
+            }
+        })
+    }
+}
+
+// shoutInput and shoutResult are the types of a tool registered the way a
+// downstream package would
+type shoutInput struct {
+    Word string `json:"word" description:"The word to shout"`
+}
+
+func (in shoutInput) Validate() error {
+    if strings.TrimSpace(in.Word) == "" {
+        return errors.New("word must not be blank")
+    }
+    return nil
+}
+
+type shoutResult struct {
+    Shout string `json:"shout"`
+}
+
+var errWhisper = errors.New("cannot shout a whisper")
+
+func TestAddTool(t *testing.T) {
+    h := newTestHandler()
+    AddTool(h, models.Tool{Name: "shout", Description: "Shout a word"}, func(ctx context.Context, in shoutInput) (shoutResult, error) {
+        if in.Word == "psst" {
+            return shoutResult{}, errWhisper
+        }
+        return shoutResult{Shout: strings.ToUpper(in.Word) + "!"}, nil
+    })
+
+    // The schemas are derived from the input and result types
+    tool := listedTools(t, h)["shout"]
+    inputSchema := tool["inputSchema"].(map[string]interface{})
+    if !reflect.DeepEqual(inputSchema["required"], []interface{}{"word"}) {
+        t.Errorf("input required = %v, want [word]", inputSchema["required"])
+    }
+    outputSchema := tool["outputSchema"].(map[string]interface{})
+    if _, ok := outputSchema["properties"].(map[string]interface{})["shout"]; !ok {
+        t.Errorf("outputSchema = %v, want a shout property", outputSchema)
+    }
+
+    session := newReadySession(h)
+    shout := func(arguments map[string]interface{}) map[string]interface{} {
+        return call(t, h, session, "tools/call", map[string]interface{}{"name": "shout", "arguments": arguments})
+    }
+
+    r := result(t, shout(map[string]interface{}{"word": "hello"}))
+    if structured := r["structuredContent"].(map[string]interface{}); structured["shout"] != "HELLO!" {
+        t.Errorf("structuredContent = %v, want shout HELLO!", structured)
+    }
+
+    for name, arguments := range map[string]map[string]interface{}{
+        "schema violation": {"word": 3},
+        "failed Validate":  {"word": " "},
+    } {
+        if code := errorCode(shout(arguments)); code != -32602 {
+            t.Errorf("%s: error code = %d, want -32602", name, code)
+        }
+    }
+
+    r = result(t, shout(map[string]interface{}{"word": "psst"}))
+    content := r["content"].([]interface{})[0].(map[string]interface{})
+    if r["isError"] != true || content["text"] != errWhisper.Error() {
+        t.Errorf("failed call = %v, want the handler error as a tool error", r)
+    }
+}
+
+func TestAddToolReplaces(t *testing.T) {
+    h := newTestHandler()
+    before := len(listedTools(t, h))
+
+    AddTool(h, models.Tool{Name: "read_graph", Description: "Replaced"}, func(ctx context.Context, in struct{}) (shoutResult, error) {
+        return shoutResult{Shout: "replaced"}, nil
+    })
+
+    tools := listedTools(t, h)
+    if len(tools) != before || tools["read_graph"]["description"] != "Replaced" {
+        t.Errorf("after replacing read_graph: %d tools, description %v; want %d, Replaced", len(tools), tools["read_graph"]["description"], before)
+    }
+    r := result(t, call(t, h, newReadySession(h), "tools/call", map[string]interface{}{"name": "read_graph", "arguments": map[string]interface{}{}}))
+    if r["structuredContent"].(map[string]interface{})["shout"] != "replaced" {
+        t.Errorf("read_graph = %v, want the replacement's result", r)
+    }
+}
+
+func TestCallsTimeout(t *testing.T) {
+    h := newTestHandler()
+    h.SetDefaultToolTimeout(10 * time.Second)
+    if err := h.SetToolTimeout("read_graph", time.Minute); err != nil {
+        t.Fatal(err)
+    }
+
+    tests := []struct {
+        name string
+        body string
+        want time.Duration
+    }{
+        {"tool timeout", `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"read_graph"}}`, time.Minute},
+        {"default timeout", `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search_nodes"}}`, 10 * time.Second},
+        {"unknown tool", `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"nope"}}`, 0},
+        {"not a tool call", `{"jsonrpc":"2.0","id":1,"method":"ping"}`, 0},
+        {"batch", `[
+            {"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"read_graph"}},
+            {"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"open_nodes"}},
+            {"jsonrpc":"2.0","id":3}
+        ]`, time.Minute + 10*time.Second},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            messages, _, errResponse := parseMessages([]byte(tt.body))
+            if errResponse != nil {
+                t.Fatalf("parseMessages: %+v", errResponse.Error)
+            }
+            if got := h.tools.callsTimeout(messages); got != tt.want {
+                t.Errorf("callsTimeout = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
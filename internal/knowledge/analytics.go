@@ -0,0 +1,283 @@
+package knowledge
+
+import (
+	"context"
+	"math"
+	"mcp-compose-memory/internal/models"
+	"sort"
+	"sync"
+)
+
+const (
+	// pageRankDamping is the probability that a random walk follows a
+	// relation rather than jumping to a random entity
+	pageRankDamping = 0.85
+
+	// pageRankIterations and pageRankTolerance bound the power iteration:
+	// it stops early once the ranks change by less than the tolerance
+	pageRankIterations = 100
+	pageRankTolerance  = 1e-9
+)
+
+// analyticsCache holds results computed from the whole graph until the
+// next write through the Manager. Writes made by other processes sharing
+// the store are not noticed.
+type analyticsCache struct {
+	mu          sync.Mutex
+	generation  uint64
+	stats       *models.GraphStats
+	communities *models.Communities
+}
+
+// invalidate discards the cached results
+func (c *analyticsCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.stats = nil
+	c.communities = nil
+}
+
+// load calls fn to read the cached results under the lock and returns the
+// generation to save freshly computed results under
+func (c *analyticsCache) load(fn func()) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fn()
+	return c.generation
+}
+
+// save calls fn to cache results computed from the graph as of generation,
+// unless a write has happened since
+func (c *analyticsCache) save(generation uint64, fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation == generation {
+		fn()
+	}
+}
+
+// GraphStats returns the degrees and PageRank of every entity, the weakly
+// connected components, and the entities without relations. The result is
+// cached until the next write and must not be modified.
+func (m *Manager) GraphStats(ctx context.Context) (*models.GraphStats, error) {
+	var stats *models.GraphStats
+	generation := m.analytics.load(func() { stats = m.analytics.stats })
+	if stats != nil {
+		return stats, nil
+	}
+
+	graph, err := m.store.ReadGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err = computeGraphStats(ctx, graph)
+	if err != nil {
+		return nil, err
+	}
+
+	m.analytics.save(generation, func() { m.analytics.stats = stats })
+	return stats, nil
+}
+
+// graphIndex numbers the entities of a graph in name order and keeps the
+// relations between entities that exist as pairs of those numbers, along
+// with their relation types
+type graphIndex struct {
+	names     []string
+	types     []string
+	index     map[string]int
+	edges     [][2]int
+	edgeTypes []string
+}
+
+func indexGraph(graph *models.KnowledgeGraph) *graphIndex {
+	entities := append([]models.Entity{}, graph.Entities...)
+	sort.Slice(entities, func(i, j int) bool { return entities[i].Name < entities[j].Name })
+
+	g := &graphIndex{index: make(map[string]int)}
+	for _, entity := range entities {
+		if _, ok := g.index[entity.Name]; ok {
+			continue
+		}
+		g.index[entity.Name] = len(g.names)
+		g.names = append(g.names, entity.Name)
+		g.types = append(g.types, entity.EntityType)
+	}
+
+	for _, relation := range graph.Relations {
+		from, ok := g.index[relation.From]
+		if !ok {
+			continue
+		}
+		to, ok := g.index[relation.To]
+		if !ok {
+			continue
+		}
+		g.edges = append(g.edges, [2]int{from, to})
+		g.edgeTypes = append(g.edgeTypes, relation.RelationType)
+	}
+	return g
+}
+
+func computeGraphStats(ctx context.Context, graph *models.KnowledgeGraph) (*models.GraphStats, error) {
+	g := indexGraph(graph)
+	n := len(g.names)
+
+	inDegree := make([]int, n)
+	outDegree := make([]int, n)
+	for _, edge := range g.edges {
+		outDegree[edge[0]]++
+		inDegree[edge[1]]++
+	}
+
+	ranks, err := pageRank(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+
+	components, membership := weaklyConnectedComponents(g)
+
+	stats := &models.GraphStats{
+		EntityCount:    n,
+		RelationCount:  len(g.edges),
+		ComponentCount: len(components),
+		Entities:       make([]models.EntityStats, n),
+		Components:     components,
+		Orphans:        []string{},
+	}
+	for i, name := range g.names {
+		stats.Entities[i] = models.EntityStats{
+			Name:       name,
+			EntityType: g.types[i],
+			InDegree:   inDegree[i],
+			OutDegree:  outDegree[i],
+			PageRank:   ranks[i],
+			Component:  membership[i],
+		}
+		if inDegree[i] == 0 && outDegree[i] == 0 {
+			stats.Orphans = append(stats.Orphans, name)
+		}
+	}
+	stats.OrphanCount = len(stats.Orphans)
+
+	// Names are already in order, so a stable sort keeps ties by name
+	sort.SliceStable(stats.Entities, func(i, j int) bool {
+		return stats.Entities[i].PageRank > stats.Entities[j].PageRank
+	})
+
+	return stats, nil
+}
+
+// pageRank computes the PageRank of each entity by power iteration. A
+// relation passes rank from its source to its target, and entities without
+// outgoing relations spread their rank over every entity.
+func pageRank(ctx context.Context, g *graphIndex) ([]float64, error) {
+	n := len(g.names)
+	if n == 0 {
+		return nil, nil
+	}
+
+	outgoing := make([][]int, n)
+	for _, edge := range g.edges {
+		outgoing[edge[0]] = append(outgoing[edge[0]], edge[1])
+	}
+
+	ranks := make([]float64, n)
+	for i := range ranks {
+		ranks[i] = 1 / float64(n)
+	}
+
+	next := make([]float64, n)
+	for iteration := 0; iteration < pageRankIterations; iteration++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		dangling := 0.0
+		for i := range next {
+			next[i] = 0
+		}
+		for i, targets := range outgoing {
+			if len(targets) == 0 {
+				dangling += ranks[i]
+				continue
+			}
+			share := ranks[i] / float64(len(targets))
+			for _, target := range targets {
+				next[target] += share
+			}
+		}
+
+		base := (1-pageRankDamping)/float64(n) + pageRankDamping*dangling/float64(n)
+		change := 0.0
+		for i := range next {
+			next[i] = base + pageRankDamping*next[i]
+			change += math.Abs(next[i] - ranks[i])
+		}
+		ranks, next = next, ranks
+
+		if change < pageRankTolerance {
+			break
+		}
+	}
+
+	return ranks, nil
+}
+
+// weaklyConnectedComponents groups the entities joined by relations in
+// either direction. Components are numbered by descending size, then by
+// their first entity name, and membership gives each entity's component.
+func weaklyConnectedComponents(g *graphIndex) ([]models.Component, []int) {
+	n := len(g.names)
+
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	for _, edge := range g.edges {
+		a, b := find(edge[0]), find(edge[1])
+		if a != b {
+			parent[a] = b
+		}
+	}
+
+	// Entities are visited in name order, so members come out sorted
+	byRoot := make(map[int][]int)
+	var roots []int
+	for i := 0; i < n; i++ {
+		root := find(i)
+		if _, ok := byRoot[root]; !ok {
+			roots = append(roots, root)
+		}
+		byRoot[root] = append(byRoot[root], i)
+	}
+	sort.SliceStable(roots, func(i, j int) bool {
+		return len(byRoot[roots[i]]) > len(byRoot[roots[j]])
+	})
+
+	components := make([]models.Component, len(roots))
+	membership := make([]int, n)
+	for id, root := range roots {
+		members := byRoot[root]
+		component := models.Component{ID: id, Size: len(members), Entities: make([]string, len(members))}
+		for i, member := range members {
+			component.Entities[i] = g.names[member]
+			membership[member] = id
+		}
+		components[id] = component
+	}
+
+	return components, membership
+}
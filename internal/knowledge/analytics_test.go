@@ -0,0 +1,126 @@
+package knowledge
+
+import (
+	"context"
+	"math"
+	"mcp-compose-memory/internal/models"
+	"reflect"
+	"testing"
+)
+
+// newStatsManager returns a manager over a star of four spokes pointing at
+// a hub, a separate pair and an entity without relations
+func newStatsManager(t *testing.T) *Manager {
+	t.Helper()
+
+	return newTestManager(t, []models.Relation{
+		{From: "s1", To: "hub", RelationType: "points_at"},
+		{From: "s2", To: "hub", RelationType: "points_at"},
+		{From: "s3", To: "hub", RelationType: "points_at"},
+		{From: "s4", To: "hub", RelationType: "points_at"},
+		{From: "x", To: "y", RelationType: "points_at"},
+	}, "loner")
+}
+
+func TestGraphStatsPageRank(t *testing.T) {
+	stats, err := newStatsManager(t).GraphStats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := 0.0
+	for _, entity := range stats.Entities {
+		sum += entity.PageRank
+	}
+	if math.Abs(sum-1) > 1e-6 {
+		t.Errorf("PageRank sums to %v, want 1", sum)
+	}
+
+	hub := stats.Entities[0]
+	if hub.Name != "hub" {
+		t.Fatalf("highest ranked entity = %s, want hub", hub.Name)
+	}
+	if hub.InDegree != 4 || hub.OutDegree != 0 {
+		t.Errorf("hub degrees = %d in, %d out; want 4 in, 0 out", hub.InDegree, hub.OutDegree)
+	}
+	if next := stats.Entities[1]; next.PageRank >= hub.PageRank {
+		t.Errorf("%s ranks %v, not below the hub's %v", next.Name, next.PageRank, hub.PageRank)
+	}
+}
+
+func TestGraphStatsComponents(t *testing.T) {
+	stats, err := newStatsManager(t).GraphStats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.EntityCount != 8 || stats.RelationCount != 5 {
+		t.Errorf("counts = %d entities, %d relations; want 8, 5", stats.EntityCount, stats.RelationCount)
+	}
+	if stats.ComponentCount != 3 {
+		t.Errorf("ComponentCount = %d, want 3", stats.ComponentCount)
+	}
+
+	var sizes []int
+	var members [][]string
+	for _, component := range stats.Components {
+		sizes = append(sizes, component.Size)
+		members = append(members, component.Entities)
+	}
+	if want := []int{5, 2, 1}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("component sizes = %v, want %v", sizes, want)
+	}
+	if want := [][]string{{"hub", "s1", "s2", "s3", "s4"}, {"x", "y"}, {"loner"}}; !reflect.DeepEqual(members, want) {
+		t.Errorf("components = %v, want %v", members, want)
+	}
+	if !reflect.DeepEqual(stats.Orphans, []string{"loner"}) {
+		t.Errorf("orphans = %v, want [loner]", stats.Orphans)
+	}
+
+	// Each entity is labelled with the component that lists it
+	byID := make(map[int][]string)
+	for _, component := range stats.Components {
+		byID[component.ID] = component.Entities
+	}
+	for _, entity := range stats.Entities {
+		found := false
+		for _, name := range byID[entity.Component] {
+			found = found || name == entity.Name
+		}
+		if !found {
+			t.Errorf("%s is labelled with component %d, which does not hold it", entity.Name, entity.Component)
+		}
+	}
+}
+
+func TestGraphStatsCache(t *testing.T) {
+	m := newStatsManager(t)
+	ctx := context.Background()
+
+	first, err := m.GraphStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached, err := m.GraphStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached != first {
+		t.Error("second GraphStats was computed again rather than cached")
+	}
+
+	// The write emits a change event, which discards the cached result
+	if _, err := m.CreateRelations(ctx, []models.Relation{{From: "y", To: "loner", RelationType: "points_at"}}); err != nil {
+		t.Fatal(err)
+	}
+	after, err := m.GraphStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after == first {
+		t.Fatal("GraphStats returned the cached result after a write")
+	}
+	if after.ComponentCount != 2 || after.OrphanCount != 0 {
+		t.Errorf("after the write: %d components, %d orphans; want 2, 0", after.ComponentCount, after.OrphanCount)
+	}
+}
@@ -0,0 +1,307 @@
+package knowledge
+
+import (
+	"context"
+	"mcp-compose-memory/internal/models"
+	"sort"
+)
+
+const (
+	// communityTopTypes is how many of the most frequent entity and
+	// relation types are reported for each community
+	communityTopTypes = 5
+
+	// louvainMaxPasses bounds the passes over the entities in each level
+	// of the Louvain method, which normally settles in a few
+	louvainMaxPasses = 100
+
+	// louvainMinGain is the least modularity gain worth moving an entity
+	// for, so that rounding errors do not keep entities moving
+	louvainMinGain = 1e-12
+)
+
+// DetectCommunities groups entities into communities that are densely
+// related within and sparsely related to each other, using the Louvain
+// method to maximize modularity. Relations are treated as undirected, and
+// each community is described by its most frequent entity and relation
+// types. The result is cached until the next write and must not be
+// modified.
+func (m *Manager) DetectCommunities(ctx context.Context) (*models.Communities, error) {
+	var communities *models.Communities
+	generation := m.analytics.load(func() { communities = m.analytics.communities })
+	if communities != nil {
+		return communities, nil
+	}
+
+	graph, err := m.store.ReadGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	communities, err = computeCommunities(ctx, graph)
+	if err != nil {
+		return nil, err
+	}
+
+	m.analytics.save(generation, func() { m.analytics.communities = communities })
+	return communities, nil
+}
+
+// weightedEdge joins a node of a louvainGraph to a neighbor
+type weightedEdge struct {
+	to     int
+	weight float64
+}
+
+// louvainGraph is an undirected weighted graph. Each edge appears in the
+// neighbor lists of both its ends, so a self-loop holds twice its weight,
+// and the degree of a node is the sum of its edge weights.
+type louvainGraph struct {
+	neighbors [][]weightedEdge
+	degree    []float64
+	// total is the sum of all degrees, twice the total edge weight
+	total float64
+}
+
+func newLouvainGraph(n int, weights []map[int]float64) *louvainGraph {
+	g := &louvainGraph{
+		neighbors: make([][]weightedEdge, n),
+		degree:    make([]float64, n),
+	}
+	for i, edges := range weights {
+		for j, weight := range edges {
+			g.neighbors[i] = append(g.neighbors[i], weightedEdge{to: j, weight: weight})
+			g.degree[i] += weight
+		}
+		// Visit neighbors in a fixed order so that results are repeatable
+		sort.Slice(g.neighbors[i], func(a, b int) bool { return g.neighbors[i][a].to < g.neighbors[i][b].to })
+		g.total += g.degree[i]
+	}
+	return g
+}
+
+func computeCommunities(ctx context.Context, graph *models.KnowledgeGraph) (*models.Communities, error) {
+	index := indexGraph(graph)
+	n := len(index.names)
+
+	weights := make([]map[int]float64, n)
+	for i := range weights {
+		weights[i] = make(map[int]float64)
+	}
+	for _, edge := range index.edges {
+		weights[edge[0]][edge[1]]++
+		weights[edge[1]][edge[0]]++
+	}
+	original := newLouvainGraph(n, weights)
+
+	// membership maps each entity to its community in the current level
+	membership := make([]int, n)
+	for i := range membership {
+		membership[i] = i
+	}
+
+	g := original
+	for {
+		communities, moved, err := louvainLevel(ctx, g)
+		if err != nil {
+			return nil, err
+		}
+		if !moved {
+			break
+		}
+
+		count := 0
+		for _, c := range communities {
+			if c+1 > count {
+				count = c + 1
+			}
+		}
+		for i, node := range membership {
+			membership[i] = communities[node]
+		}
+		g = aggregate(g, communities, count)
+	}
+
+	return describeCommunities(index, original, membership), nil
+}
+
+// louvainLevel moves each node to the neighboring community with the
+// largest modularity gain until no move helps. It returns the community of
+// each node, numbered from zero in order of first appearance, and whether
+// any node moved.
+func louvainLevel(ctx context.Context, g *louvainGraph) ([]int, bool, error) {
+	n := len(g.degree)
+	community := make([]int, n)
+	totals := make([]float64, n)
+	for i := range community {
+		community[i] = i
+		totals[i] = g.degree[i]
+	}
+
+	moved := false
+	if g.total > 0 {
+		links := make(map[int]float64)
+		var candidates []int
+
+		for pass := 0; pass < louvainMaxPasses; pass++ {
+			if err := ctx.Err(); err != nil {
+				return nil, false, err
+			}
+
+			changed := false
+			for i := 0; i < n; i++ {
+				current := community[i]
+
+				// Weight of the edges from i to each neighboring community
+				for c := range links {
+					delete(links, c)
+				}
+				candidates = candidates[:0]
+				for _, edge := range g.neighbors[i] {
+					if edge.to == i {
+						continue
+					}
+					c := community[edge.to]
+					if _, ok := links[c]; !ok {
+						candidates = append(candidates, c)
+					}
+					links[c] += edge.weight
+				}
+
+				totals[current] -= g.degree[i]
+				best := current
+				bestGain := links[current] - totals[current]*g.degree[i]/g.total
+				for _, c := range candidates {
+					gain := links[c] - totals[c]*g.degree[i]/g.total
+					if gain > bestGain+louvainMinGain {
+						best, bestGain = c, gain
+					}
+				}
+				totals[best] += g.degree[i]
+
+				if best != current {
+					community[i] = best
+					changed, moved = true, true
+				}
+			}
+			if !changed {
+				break
+			}
+		}
+	}
+
+	// Renumber the communities densely
+	renumbered := make(map[int]int)
+	for i, c := range community {
+		id, ok := renumbered[c]
+		if !ok {
+			id = len(renumbered)
+			renumbered[c] = id
+		}
+		community[i] = id
+	}
+
+	return community, moved, nil
+}
+
+// aggregate builds the graph whose nodes are the communities of g, so that
+// the next level of the Louvain method moves whole communities
+func aggregate(g *louvainGraph, community []int, count int) *louvainGraph {
+	weights := make([]map[int]float64, count)
+	for i := range weights {
+		weights[i] = make(map[int]float64)
+	}
+	for i, edges := range g.neighbors {
+		for _, edge := range edges {
+			weights[community[i]][community[edge.to]] += edge.weight
+		}
+	}
+	return newLouvainGraph(count, weights)
+}
+
+// describeCommunities lists the communities by descending size with their
+// members in name order, and computes the modularity of the partition
+func describeCommunities(index *graphIndex, g *louvainGraph, membership []int) *models.Communities {
+	members := make(map[int][]int)
+	var order []int
+	for i, c := range membership {
+		if _, ok := members[c]; !ok {
+			order = append(order, c)
+		}
+		members[c] = append(members[c], i)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(members[order[i]]) > len(members[order[j]])
+	})
+
+	id := make(map[int]int)
+	for i, c := range order {
+		id[c] = i
+	}
+
+	result := &models.Communities{
+		CommunityCount: len(order),
+		Communities:    make([]models.Community, len(order)),
+	}
+
+	entityTypes := make([]map[string]int, len(order))
+	relationTypes := make([]map[string]int, len(order))
+	for i, c := range order {
+		entityTypes[i] = make(map[string]int)
+		relationTypes[i] = make(map[string]int)
+
+		community := models.Community{ID: i, Size: len(members[c]), Members: make([]string, len(members[c]))}
+		for j, member := range members[c] {
+			community.Members[j] = index.names[member]
+			entityTypes[i][index.types[member]]++
+		}
+		result.Communities[i] = community
+	}
+	for e, edge := range index.edges {
+		if membership[edge[0]] == membership[edge[1]] {
+			relationTypes[id[membership[edge[0]]]][index.edgeTypes[e]]++
+		}
+	}
+	for i := range result.Communities {
+		result.Communities[i].EntityTypes = topTypes(entityTypes[i])
+		result.Communities[i].RelationTypes = topTypes(relationTypes[i])
+	}
+
+	// Q = sum over communities of internal/2m - (total/2m)^2
+	if g.total > 0 {
+		internal := make(map[int]float64)
+		totals := make(map[int]float64)
+		for i, edges := range g.neighbors {
+			totals[membership[i]] += g.degree[i]
+			for _, edge := range edges {
+				if membership[edge.to] == membership[i] {
+					internal[membership[i]] += edge.weight
+				}
+			}
+		}
+		for _, c := range order {
+			share := totals[c] / g.total
+			result.Modularity += internal[c]/g.total - share*share
+		}
+	}
+
+	return result
+}
+
+// topTypes returns the most frequent types, ties broken by name
+func topTypes(counts map[string]int) []models.TypeCount {
+	types := make([]models.TypeCount, 0, len(counts))
+	for name, count := range counts {
+		types = append(types, models.TypeCount{Type: name, Count: count})
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if types[i].Count != types[j].Count {
+			return types[i].Count > types[j].Count
+		}
+		return types[i].Type < types[j].Type
+	})
+	if len(types) > communityTopTypes {
+		types = types[:communityTopTypes]
+	}
+	return types
+}
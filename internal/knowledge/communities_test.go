@@ -0,0 +1,92 @@
+package knowledge
+
+import (
+	"context"
+	"mcp-compose-memory/internal/models"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// cliqueRelations relates every pair of names
+func cliqueRelations(names ...string) []models.Relation {
+	var relations []models.Relation
+	for i, from := range names {
+		for _, to := range names[i+1:] {
+			relations = append(relations, models.Relation{From: from, To: to, RelationType: "knows"})
+		}
+	}
+	return relations
+}
+
+func communityMembers(communities *models.Communities) [][]string {
+	var members [][]string
+	for _, community := range communities.Communities {
+		names := append([]string{}, community.Members...)
+		sort.Strings(names)
+		members = append(members, names)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i][0] < members[j][0] })
+	return members
+}
+
+func TestDetectCommunitiesTwoCliques(t *testing.T) {
+	relations := append(cliqueRelations("a1", "a2", "a3", "a4"), cliqueRelations("b1", "b2", "b3", "b4")...)
+	// A single bridge should not merge the cliques
+	relations = append(relations, models.Relation{From: "a1", To: "b1", RelationType: "bridges"})
+	// An entity without relations forms a community of its own
+	m := newTestManager(t, relations, "loner")
+
+	communities, err := m.DetectCommunities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{{"a1", "a2", "a3", "a4"}, {"b1", "b2", "b3", "b4"}, {"loner"}}
+	if got := communityMembers(communities); !reflect.DeepEqual(got, want) {
+		t.Errorf("communities = %v, want %v", got, want)
+	}
+	if communities.CommunityCount != 3 {
+		t.Errorf("CommunityCount = %d, want 3", communities.CommunityCount)
+	}
+	// Two 6-edge cliques and a bridge: 2 * (6/13 - (13/26)^2)
+	if want := 2 * (6.0/13 - 0.25); communities.Modularity < want-1e-9 || communities.Modularity > want+1e-9 {
+		t.Errorf("Modularity = %v, want %v", communities.Modularity, want)
+	}
+
+	for _, community := range communities.Communities {
+		if community.Size == 4 {
+			if len(community.EntityTypes) != 1 || community.EntityTypes[0] != (models.TypeCount{Type: "node", Count: 4}) {
+				t.Errorf("entity types = %v, want 4 node", community.EntityTypes)
+			}
+			if len(community.RelationTypes) != 1 || community.RelationTypes[0] != (models.TypeCount{Type: "knows", Count: 6}) {
+				t.Errorf("relation types = %v, want 6 knows", community.RelationTypes)
+			}
+		}
+	}
+}
+
+func TestDetectCommunitiesAfterWrite(t *testing.T) {
+	m := newTestManager(t, cliqueRelations("a1", "a2", "a3"))
+	ctx := context.Background()
+
+	before, err := m.DetectCommunities(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.CommunityCount != 1 {
+		t.Fatalf("CommunityCount = %d, want 1", before.CommunityCount)
+	}
+
+	// A write invalidates the cached result
+	if _, err := m.CreateEntities(ctx, []models.Entity{{Name: "loner", EntityType: "node"}}); err != nil {
+		t.Fatal(err)
+	}
+	after, err := m.DetectCommunities(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.CommunityCount != 2 {
+		t.Errorf("CommunityCount = %d, want 2", after.CommunityCount)
+	}
+}
@@ -0,0 +1,43 @@
+package knowledge
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Kinds of Error. Test for them with errors.Is.
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrInvalidArgument = errors.New("invalid argument")
+	ErrTimeout         = errors.New("timeout")
+)
+
+// Error is a failure caused by the request, such as naming an entity that
+// does not exist, as opposed to a failure of the storage backend. Its
+// message is meant to be shown to the caller.
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// TimeoutError reports that op did not finish within timeout
+func TimeoutError(op string, timeout time.Duration) error {
+	return &Error{Kind: ErrTimeout, Message: fmt.Sprintf("%s timed out after %s", op, timeout)}
+}
+
+func notFoundf(format string, args ...interface{}) error {
+	return &Error{Kind: ErrNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+func invalidArgumentf(format string, args ...interface{}) error {
+	return &Error{Kind: ErrInvalidArgument, Message: fmt.Sprintf(format, args...)}
+}
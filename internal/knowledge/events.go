@@ -0,0 +1,83 @@
+package knowledge
+
+import "sync"
+
+// ChangeEvent describes a successful write to the knowledge graph in terms
+// of the entities it affected. An entity is Updated when its observations
+// or its incident relations changed.
+type ChangeEvent struct {
+	Created []string
+	Updated []string
+	Deleted []string
+}
+
+func (e ChangeEvent) empty() bool {
+	return len(e.Created) == 0 && len(e.Updated) == 0 && len(e.Deleted) == 0
+}
+
+// ChangeListener is called synchronously after each write, so it must not
+// block or call back into the Manager.
+type ChangeListener func(ChangeEvent)
+
+type listeners struct {
+	mu    sync.RWMutex
+	next  int
+	funcs map[int]ChangeListener
+}
+
+// OnChange registers a listener for change events and returns a function
+// that unregisters it.
+func (m *Manager) OnChange(listener ChangeListener) func() {
+	m.listeners.mu.Lock()
+	defer m.listeners.mu.Unlock()
+
+	if m.listeners.funcs == nil {
+		m.listeners.funcs = make(map[int]ChangeListener)
+	}
+	id := m.listeners.next
+	m.listeners.next++
+	m.listeners.funcs[id] = listener
+
+	return func() {
+		m.listeners.mu.Lock()
+		defer m.listeners.mu.Unlock()
+		delete(m.listeners.funcs, id)
+	}
+}
+
+func (m *Manager) hasListeners() bool {
+	m.listeners.mu.RLock()
+	defer m.listeners.mu.RUnlock()
+	return len(m.listeners.funcs) > 0
+}
+
+func (m *Manager) emit(event ChangeEvent) {
+	if event.empty() {
+		return
+	}
+	m.analytics.invalidate()
+
+	m.listeners.mu.RLock()
+	funcs := make([]ChangeListener, 0, len(m.listeners.funcs))
+	for _, listener := range m.listeners.funcs {
+		funcs = append(funcs, listener)
+	}
+	m.listeners.mu.RUnlock()
+
+	for _, listener := range funcs {
+		listener(event)
+	}
+}
+
+// uniqueNames returns names without duplicates, keeping the first occurrence
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
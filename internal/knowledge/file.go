@@ -0,0 +1,421 @@
+package knowledge
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"mcp-compose-memory/internal/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileStore persists the knowledge graph to a line-delimited JSON file in
+// the format used by the reference MCP memory server, so the same
+// memory.json can be shared by either server. Every operation holds an
+// advisory lock on a sidecar .lock file, reloads the file if another
+// process changed it, and writes changes back with an atomic rename.
+// Records of types it does not know are kept and written back unchanged.
+//
+// The lock only coordinates instances of this server: the reference server
+// does not take it, so a write it makes while this store is writing can be
+// lost. Changes it makes at other times are noticed even when they leave
+// the size and modification time of the file as they were.
+type FileStore struct {
+	path     string
+	mu       sync.Mutex
+	mem      *MemoryStore
+	lockPath string
+
+	// unknown holds the records of unknown types, in file order
+	unknown []json.RawMessage
+
+	// info, sum and seen describe the file as last loaded or saved: its
+	// metadata, the hash of its content, and when it was read. A nil info
+	// forces the next operation to load the file.
+	info os.FileInfo
+	sum  [sha256.Size]byte
+	seen time.Time
+}
+
+// mtimeResolution is the coarsest modification time resolution of the
+// file systems the memory file may live on. A file modified this recently
+// may change again without its modification time changing.
+const mtimeResolution = 2 * time.Second
+
+var _ Store = (*FileStore)(nil)
+
+// fileRecord is one line of the memory file.
+type fileRecord struct {
+	Type         string   `json:"type"`
+	Name         string   `json:"name,omitempty"`
+	EntityType   string   `json:"entityType,omitempty"`
+	Observations []string `json:"observations,omitempty"`
+	From         string   `json:"from,omitempty"`
+	To           string   `json:"to,omitempty"`
+	RelationType string   `json:"relationType,omitempty"`
+}
+
+type fileEntity struct {
+	Type         string   `json:"type"`
+	Name         string   `json:"name"`
+	EntityType   string   `json:"entityType"`
+	Observations []string `json:"observations"`
+}
+
+type fileRelation struct {
+	Type         string `json:"type"`
+	From         string `json:"from"`
+	To           string `json:"to"`
+	RelationType string `json:"relationType"`
+}
+
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path:     path,
+		lockPath: path + ".lock",
+		mem:      NewMemoryStore(),
+	}
+
+	// Load once up front so a malformed file fails at startup
+	if err := s.withLock(context.Background(), false, func() error { return nil }); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileStore) Close() error {
+	return nil
+}
+
+// withLock runs fn against an up-to-date copy of the graph while holding the
+// file lock. When write is true the lock is exclusive and the graph is
+// saved after fn succeeds. A write cancelled by ctx is discarded rather
+// than saved.
+func (s *FileStore) withLock(ctx context.Context, write bool, fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	lock, err := os.OpenFile(s.lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open lock file: %w", err)
+	}
+	defer lock.Close()
+
+	if err := lockFile(lock, write); err != nil {
+		return fmt.Errorf("failed to lock memory file: %w", err)
+	}
+	defer unlockFile(lock)
+
+	if err := s.reloadIfChanged(); err != nil {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		// Force a reload so a half-applied change is not kept in memory
+		s.info = nil
+		return err
+	}
+
+	if write {
+		if err := ctx.Err(); err != nil {
+			s.info = nil
+			return err
+		}
+		return s.save()
+	}
+	return nil
+}
+
+func (s *FileStore) reloadIfChanged() error {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		s.mem, s.unknown = NewMemoryStore(), nil
+		s.info = nil
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Metadata alone is trusted only once the file was already older than
+	// the modification time resolution when it was read, since a same-size
+	// rewrite within that window leaves it unchanged
+	if s.info != nil && os.SameFile(info, s.info) &&
+		info.ModTime().Equal(s.info.ModTime()) && info.Size() == s.info.Size() &&
+		s.seen.Sub(info.ModTime()) > mtimeResolution {
+		return nil
+	}
+
+	seen := time.Now()
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if s.info != nil && sum == s.sum {
+		s.info, s.seen = info, seen
+		return nil
+	}
+
+	mem, unknown, err := parseMemoryFile(s.path, data)
+	if err != nil {
+		return err
+	}
+
+	s.mem, s.unknown = mem, unknown
+	s.info, s.sum, s.seen = info, sum, seen
+	return nil
+}
+
+// parseMemoryFile decodes the content of the memory file at path,
+// returning the records of unknown types separately
+func parseMemoryFile(path string, data []byte) (*MemoryStore, []json.RawMessage, error) {
+	mem := NewMemoryStore()
+	var unknown []json.RawMessage
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var record fileRecord
+		if err := json.Unmarshal(text, &record); err != nil {
+			return nil, nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+
+		switch record.Type {
+		case "entity":
+			mem.entities[record.Name] = &models.Entity{
+				Name:         record.Name,
+				EntityType:   record.EntityType,
+				Observations: append([]string{}, record.Observations...),
+			}
+			mem.touch(record.Name)
+		case "relation":
+			// Relations are kept even if an endpoint is missing so that
+			// rewriting the file never drops data the other server wrote.
+			mem.relations = append(mem.relations, models.Relation{
+				From:         record.From,
+				To:           record.To,
+				RelationType: record.RelationType,
+			})
+		default:
+			// Kept for the same reason; the scanner reuses its buffer
+			unknown = append(unknown, append(json.RawMessage{}, text...))
+		}
+	}
+
+	return mem, unknown, scanner.Err()
+}
+
+// save writes the graph to a temporary file next to the memory file and
+// renames it into place, so readers never observe a partial write.
+func (s *FileStore) save() error {
+	names := make([]string, 0, len(s.mem.entities))
+	for name := range s.mem.entities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines [][]byte
+	for _, name := range names {
+		entity := s.mem.entities[name]
+		line, err := json.Marshal(fileEntity{
+			Type:         "entity",
+			Name:         entity.Name,
+			EntityType:   entity.EntityType,
+			Observations: append([]string{}, entity.Observations...),
+		})
+		if err != nil {
+			return err
+		}
+		lines = append(lines, line)
+	}
+	for _, relation := range s.mem.relations {
+		line, err := json.Marshal(fileRelation{
+			Type:         "relation",
+			From:         relation.From,
+			To:           relation.To,
+			RelationType: relation.RelationType,
+		})
+		if err != nil {
+			return err
+		}
+		lines = append(lines, line)
+	}
+	for _, record := range s.unknown {
+		lines = append(lines, record)
+	}
+	data := bytes.Join(lines, []byte("\n"))
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(s.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary memory file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace memory file: %w", err)
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	s.info, s.sum, s.seen = info, sha256.Sum256(data), time.Now()
+	return nil
+}
+
+func (s *FileStore) CreateEntities(ctx context.Context, entities []models.Entity) ([]models.Entity, error) {
+	var newEntities []models.Entity
+	err := s.withLock(ctx, true, func() (err error) {
+		newEntities, err = s.mem.CreateEntities(ctx, entities)
+		return err
+	})
+	return newEntities, err
+}
+
+func (s *FileStore) CreateRelations(ctx context.Context, relations []models.Relation) ([]models.Relation, error) {
+	var newRelations []models.Relation
+	err := s.withLock(ctx, true, func() (err error) {
+		newRelations, err = s.mem.CreateRelations(ctx, relations)
+		return err
+	})
+	return newRelations, err
+}
+
+func (s *FileStore) AddObservations(ctx context.Context, observations []models.ObservationAddition) ([]models.ObservationResult, error) {
+	var results []models.ObservationResult
+	err := s.withLock(ctx, true, func() (err error) {
+		results, err = s.mem.AddObservations(ctx, observations)
+		return err
+	})
+	return results, err
+}
+
+func (s *FileStore) DeleteEntities(ctx context.Context, entityNames []string) error {
+	return s.withLock(ctx, true, func() error {
+		return s.mem.DeleteEntities(ctx, entityNames)
+	})
+}
+
+func (s *FileStore) DeleteObservations(ctx context.Context, deletions []models.ObservationDeletion) error {
+	return s.withLock(ctx, true, func() error {
+		return s.mem.DeleteObservations(ctx, deletions)
+	})
+}
+
+func (s *FileStore) DeleteRelations(ctx context.Context, relations []models.Relation) error {
+	return s.withLock(ctx, true, func() error {
+		return s.mem.DeleteRelations(ctx, relations)
+	})
+}
+
+func (s *FileStore) ReadGraph(ctx context.Context) (*models.KnowledgeGraph, error) {
+	var graph *models.KnowledgeGraph
+	err := s.withLock(ctx, false, func() (err error) {
+		graph, err = s.mem.ReadGraph(ctx)
+		return err
+	})
+	return graph, err
+}
+
+func (s *FileStore) SearchNodes(ctx context.Context, query string) (*models.KnowledgeGraph, error) {
+	var graph *models.KnowledgeGraph
+	err := s.withLock(ctx, false, func() (err error) {
+		graph, err = s.mem.SearchNodes(ctx, query)
+		return err
+	})
+	return graph, err
+}
+
+func (s *FileStore) OpenNodes(ctx context.Context, names []string) (*models.KnowledgeGraph, error) {
+	var graph *models.KnowledgeGraph
+	err := s.withLock(ctx, false, func() (err error) {
+		graph, err = s.mem.OpenNodes(ctx, names)
+		return err
+	})
+	return graph, err
+}
+
+func (s *FileStore) Complete(ctx context.Context, field CompletionField, prefix string, limit int) ([]string, error) {
+	var values []string
+	err := s.withLock(ctx, false, func() (err error) {
+		values, err = s.mem.Complete(ctx, field, prefix, limit)
+		return err
+	})
+	return values, err
+}
+
+func (s *FileStore) ReadGraphPage(ctx context.Context, page Page) (*models.KnowledgeGraph, bool, error) {
+	var graph *models.KnowledgeGraph
+	var more bool
+	err := s.withLock(ctx, false, func() (err error) {
+		graph, more, err = s.mem.ReadGraphPage(ctx, page)
+		return err
+	})
+	return graph, more, err
+}
+
+func (s *FileStore) SearchNodesPage(ctx context.Context, query string, page Page) (*models.KnowledgeGraph, bool, error) {
+	var graph *models.KnowledgeGraph
+	var more bool
+	err := s.withLock(ctx, false, func() (err error) {
+		graph, more, err = s.mem.SearchNodesPage(ctx, query, page)
+		return err
+	})
+	return graph, more, err
+}
+
+func (s *FileStore) Neighborhood(ctx context.Context, query NeighborhoodQuery) (*models.Neighborhood, error) {
+	var neighborhood *models.Neighborhood
+	err := s.withLock(ctx, false, func() (err error) {
+		neighborhood, err = s.mem.Neighborhood(ctx, query)
+		return err
+	})
+	return neighborhood, err
+}
+
+func (s *FileStore) Adjacent(ctx context.Context, names []string, direction Direction, relationTypes []string) ([]models.Relation, error) {
+	var relations []models.Relation
+	err := s.withLock(ctx, false, func() (err error) {
+		relations, err = s.mem.Adjacent(ctx, names, direction, relationTypes)
+		return err
+	})
+	return relations, err
+}
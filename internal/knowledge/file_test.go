@@ -0,0 +1,71 @@
+package knowledge
+
+import (
+	"context"
+	"mcp-compose-memory/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileStoreKeepsUnknownRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.json")
+	unknown := `{"type":"annotation","target":"alice","note":"written by another server"}`
+	content := `{"type":"entity","name":"alice","entityType":"person","observations":[]}` + "\n" + unknown + "\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateEntities(context.Background(), []models.Entity{{Name: "bob", EntityType: "person"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), unknown) {
+		t.Errorf("rewritten file lost the unknown record:\n%s", data)
+	}
+	if !strings.Contains(string(data), `"name":"bob"`) {
+		t.Errorf("rewritten file lacks the new entity:\n%s", data)
+	}
+}
+
+func TestFileStoreNoticesSameSizeRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.json")
+	write := func(name string, modTime time.Time) {
+		t.Helper()
+		line := `{"type":"entity","name":"` + name + `","entityType":"person","observations":[]}`
+		if err := os.WriteFile(path, []byte(line), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The other server rewrites the file in place, keeping its size, within
+	// one tick of a coarse modification time
+	modTime := time.Now().Truncate(time.Second)
+	write("alice", modTime)
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("carol", modTime)
+
+	graph, err := store.ReadGraph(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := entityNames(graph.Entities); len(names) != 1 || names[0] != "carol" {
+		t.Errorf("entities = %v, want [carol]", names)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package knowledge
+
+import "os"
+
+// lockFile is a no-op on platforms without flock; FileStore still
+// serializes access within this process.
+func lockFile(f *os.File, exclusive bool) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,109 @@
+package knowledge
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"mcp-compose-memory/internal/models"
+)
+
+// LogLevel is the severity of a LogRecord, using the syslog levels of MCP
+// logging.
+type LogLevel string
+
+const (
+	LevelDebug     LogLevel = "debug"
+	LevelInfo      LogLevel = "info"
+	LevelNotice    LogLevel = "notice"
+	LevelWarning   LogLevel = "warning"
+	LevelError     LogLevel = "error"
+	LevelCritical  LogLevel = "critical"
+	LevelAlert     LogLevel = "alert"
+	LevelEmergency LogLevel = "emergency"
+)
+
+// LogRecord is a structured log message about an operation. Data holds
+// its details in machine-readable form.
+type LogRecord struct {
+	Level   LogLevel
+	Logger  string
+	Message string
+	Data    map[string]interface{}
+}
+
+// LogFunc receives the log records of the operations run under a context.
+// It is called synchronously, so it must return quickly.
+type LogFunc func(record LogRecord)
+
+type loggerKey struct{}
+
+// WithLogger returns a context under which Manager operations pass their
+// log records to fn, such as relations skipped because an endpoint does
+// not exist and the rows a write changed.
+func WithLogger(ctx context.Context, fn LogFunc) context.Context {
+	return context.WithValue(ctx, loggerKey{}, fn)
+}
+
+// Log writes record to the server log, unless it is a debug record, and
+// passes it to the LogFunc of ctx if there is one.
+func Log(ctx context.Context, record LogRecord) {
+	if record.Logger == "" {
+		record.Logger = "knowledge"
+	}
+	if record.Level != LevelDebug {
+		log.Print(record.Message)
+	}
+	if fn, ok := ctx.Value(loggerKey{}).(LogFunc); ok {
+		fn(record)
+	}
+}
+
+// logSkippedRelation reports a relation that was not created because one
+// of its endpoints does not exist
+func logSkippedRelation(ctx context.Context, relation models.Relation, fromExists, toExists bool) {
+	missing := []string{}
+	if !fromExists {
+		missing = append(missing, relation.From)
+	}
+	if !toExists && relation.To != relation.From {
+		missing = append(missing, relation.To)
+	}
+
+	Log(ctx, LogRecord{
+		Level:   LevelWarning,
+		Message: fmt.Sprintf("Skipping relation %s -> %s: entity not found", relation.From, relation.To),
+		Data: map[string]interface{}{
+			"from":            relation.From,
+			"to":              relation.To,
+			"relationType":    relation.RelationType,
+			"missingEntities": missing,
+		},
+	})
+}
+
+// rowCount totals the rows changed by the statements of one write
+type rowCount int64
+
+// add counts the rows changed by a statement, passing on its error
+func (c *rowCount) add(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil {
+		*c += rowCount(n)
+	}
+	return nil
+}
+
+// log reports the rows changed by a committed write
+func (c rowCount) log(ctx context.Context, operation string) {
+	Log(ctx, LogRecord{
+		Level:   LevelDebug,
+		Message: fmt.Sprintf("%s changed %d rows", operation, c),
+		Data: map[string]interface{}{
+			"operation":    operation,
+			"rowsAffected": int64(c),
+		},
+	})
+}
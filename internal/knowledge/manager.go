@@ -1,476 +1,224 @@
 package knowledge
 
 import (
-	"database/sql"
-	"fmt"
-	"log"
+	"context"
 	"mcp-compose-memory/internal/models"
-
-	"github.com/lib/pq"
 )
 
+// Manager is the entry point to the knowledge graph used by the MCP handlers.
+// It delegates persistence to a Store, reports every write to the
+// listeners registered with OnChange, and caches analytics between writes.
 type Manager struct {
-	db *sql.DB
-}
-
-func NewManager(db *sql.DB) *Manager {
-	return &Manager{db: db}
+	store     Store
+	listeners listeners
+	analytics analyticsCache
 }
 
-func (m *Manager) getEntityByName(tx *sql.Tx, name string) (*models.Entity, error) {
-	var entity models.Entity
-	err := tx.QueryRow("SELECT id, name, entity_type FROM entities WHERE name = $1", name).
-		Scan(&entity.ID, &entity.Name, &entity.EntityType)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return &entity, nil
+func NewManager(store Store) *Manager {
+	return &Manager{store: store}
 }
 
-func (m *Manager) getEntityObservations(tx *sql.Tx, entityID int) ([]string, error) {
-	rows, err := tx.Query("SELECT content FROM observations WHERE entity_id = $1 ORDER BY created_at", entityID)
+func (m *Manager) CreateEntities(ctx context.Context, entities []models.Entity) ([]models.Entity, error) {
+	created, err := m.store.CreateEntities(ctx, entities)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var observations []string
-	for rows.Next() {
-		var content string
-		if err := rows.Scan(&content); err != nil {
-			return nil, err
-		}
-		observations = append(observations, content)
+	var names []string
+	for _, entity := range created {
+		names = append(names, entity.Name)
 	}
+	m.emit(ChangeEvent{Created: names})
 
-	return observations, rows.Err()
+	return created, nil
 }
 
-func (m *Manager) CreateEntities(entities []models.Entity) ([]models.Entity, error) {
-	tx, err := m.db.Begin()
+func (m *Manager) CreateRelations(ctx context.Context, relations []models.Relation) ([]models.Relation, error) {
+	created, err := m.store.CreateRelations(ctx, relations)
 	if err != nil {
 		return nil, err
 	}
-	defer tx.Rollback()
-
-	var newEntities []models.Entity
-
-	for _, entity := range entities {
-		existingEntity, err := m.getEntityByName(tx, entity.Name)
-		if err != nil {
-			return nil, err
-		}
-
-		if existingEntity == nil {
-			var entityID int
-			err := tx.QueryRow("INSERT INTO entities (name, entity_type) VALUES ($1, $2) RETURNING id",
-				entity.Name, entity.EntityType).Scan(&entityID)
-			if err != nil {
-				return nil, err
-			}
-
-			for _, observation := range entity.Observations {
-				_, err := tx.Exec("INSERT INTO observations (entity_id, content) VALUES ($1, $2)",
-					entityID, observation)
-				if err != nil {
-					return nil, err
-				}
-			}
-
-			newEntities = append(newEntities, entity)
-		}
-	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, err
+	var names []string
+	for _, relation := range created {
+		names = append(names, relation.From, relation.To)
 	}
+	m.emit(ChangeEvent{Updated: uniqueNames(names)})
 
-	return newEntities, nil
+	return created, nil
 }
 
-func (m *Manager) CreateRelations(relations []models.Relation) ([]models.Relation, error) {
-	tx, err := m.db.Begin()
+func (m *Manager) AddObservations(ctx context.Context, observations []models.ObservationAddition) ([]models.ObservationResult, error) {
+	results, err := m.store.AddObservations(ctx, observations)
 	if err != nil {
 		return nil, err
 	}
-	defer tx.Rollback()
-
-	var newRelations []models.Relation
 
-	for _, relation := range relations {
-		fromEntity, err := m.getEntityByName(tx, relation.From)
-		if err != nil {
-			return nil, err
-		}
-		toEntity, err := m.getEntityByName(tx, relation.To)
-		if err != nil {
-			return nil, err
-		}
-
-		if fromEntity == nil || toEntity == nil {
-			log.Printf("Skipping relation %s -> %s: entity not found", relation.From, relation.To)
-			continue
-		}
-
-		var exists bool
-		err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM relations WHERE from_entity_id = $1 AND to_entity_id = $2 AND relation_type = $3)",
-			fromEntity.ID, toEntity.ID, relation.RelationType).Scan(&exists)
-		if err != nil {
-			return nil, err
+	var names []string
+	for _, result := range results {
+		if len(result.AddedObservations) > 0 {
+			names = append(names, result.EntityName)
 		}
-
-		if !exists {
-			_, err := tx.Exec("INSERT INTO relations (from_entity_id, to_entity_id, relation_type) VALUES ($1, $2, $3)",
-				fromEntity.ID, toEntity.ID, relation.RelationType)
-			if err != nil {
-				return nil, err
-			}
-			newRelations = append(newRelations, relation)
-		}
-	}
-
-	if err := tx.Commit(); err != nil {
-		return nil, err
 	}
+	m.emit(ChangeEvent{Updated: uniqueNames(names)})
 
-	return newRelations, nil
+	return results, nil
 }
 
-func (m *Manager) AddObservations(observations []struct {
-	EntityName string   `json:"entityName"`
-	Contents   []string `json:"contents"`
-}) ([]struct {
-	EntityName        string   `json:"entityName"`
-	AddedObservations []string `json:"addedObservations"`
-}, error) {
-	tx, err := m.db.Begin()
-	if err != nil {
-		return nil, err
-	}
-	defer tx.Rollback()
-
-	var results []struct {
-		EntityName        string   `json:"entityName"`
-		AddedObservations []string `json:"addedObservations"`
-	}
-
-	for _, obs := range observations {
-		entity, err := m.getEntityByName(tx, obs.EntityName)
+func (m *Manager) DeleteEntities(ctx context.Context, entityNames []string) error {
+	// Neighbours lose their relations to the deleted entities, so look them
+	// up before the cascade removes the evidence
+	var neighbours []string
+	if m.hasListeners() {
+		relations, err := m.store.Adjacent(ctx, entityNames, DirectionBoth, nil)
 		if err != nil {
-			return nil, err
-		}
-		if entity == nil {
-			return nil, fmt.Errorf("entity with name %s not found", obs.EntityName)
+			return err
 		}
 
-		existingObservations, err := m.getEntityObservations(tx, entity.ID)
-		if err != nil {
-			return nil, err
+		deleted := make(map[string]bool)
+		for _, name := range entityNames {
+			deleted[name] = true
 		}
-
-		var addedObservations []string
-		for _, content := range obs.Contents {
-			found := false
-			for _, existing := range existingObservations {
-				if existing == content {
-					found = true
-					break
-				}
+		for _, relation := range relations {
+			if !deleted[relation.To] {
+				neighbours = append(neighbours, relation.To)
 			}
-
-			if !found {
-				_, err := tx.Exec("INSERT INTO observations (entity_id, content) VALUES ($1, $2)",
-					entity.ID, content)
-				if err != nil {
-					return nil, err
-				}
-				addedObservations = append(addedObservations, content)
+			if !deleted[relation.From] {
+				neighbours = append(neighbours, relation.From)
 			}
 		}
-
-		results = append(results, struct {
-			EntityName        string   `json:"entityName"`
-			AddedObservations []string `json:"addedObservations"`
-		}{
-			EntityName:        obs.EntityName,
-			AddedObservations: addedObservations,
-		})
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, err
+	if err := m.store.DeleteEntities(ctx, entityNames); err != nil {
+		return err
 	}
 
-	return results, nil
+	m.emit(ChangeEvent{Updated: uniqueNames(neighbours), Deleted: uniqueNames(entityNames)})
+	return nil
 }
 
-func (m *Manager) DeleteEntities(entityNames []string) error {
-	tx, err := m.db.Begin()
-	if err != nil {
+func (m *Manager) DeleteObservations(ctx context.Context, deletions []models.ObservationDeletion) error {
+	if err := m.store.DeleteObservations(ctx, deletions); err != nil {
 		return err
 	}
-	defer tx.Rollback()
 
-	for _, name := range entityNames {
-		_, err := tx.Exec("DELETE FROM entities WHERE name = $1", name)
-		if err != nil {
-			return err
-		}
+	var names []string
+	for _, deletion := range deletions {
+		names = append(names, deletion.EntityName)
 	}
+	m.emit(ChangeEvent{Updated: uniqueNames(names)})
 
-	return tx.Commit()
+	return nil
 }
 
-func (m *Manager) DeleteObservations(deletions []struct {
-	EntityName   string   `json:"entityName"`
-	Observations []string `json:"observations"`
-}) error {
-	tx, err := m.db.Begin()
-	if err != nil {
+func (m *Manager) DeleteRelations(ctx context.Context, relations []models.Relation) error {
+	if err := m.store.DeleteRelations(ctx, relations); err != nil {
 		return err
 	}
-	defer tx.Rollback()
 
-	for _, deletion := range deletions {
-		entity, err := m.getEntityByName(tx, deletion.EntityName)
-		if err != nil {
-			return err
-		}
-		if entity != nil {
-			for _, observation := range deletion.Observations {
-				_, err := tx.Exec("DELETE FROM observations WHERE entity_id = $1 AND content = $2",
-					entity.ID, observation)
-				if err != nil {
-					return err
-				}
-			}
-		}
+	var names []string
+	for _, relation := range relations {
+		names = append(names, relation.From, relation.To)
 	}
+	m.emit(ChangeEvent{Updated: uniqueNames(names)})
 
-	return tx.Commit()
+	return nil
 }
 
-func (m *Manager) DeleteRelations(relations []models.Relation) error {
-	tx, err := m.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
+func (m *Manager) ReadGraph(ctx context.Context) (*models.KnowledgeGraph, error) {
+	return m.store.ReadGraph(ctx)
+}
 
-	for _, relation := range relations {
-		fromEntity, err := m.getEntityByName(tx, relation.From)
-		if err != nil {
-			return err
-		}
-		toEntity, err := m.getEntityByName(tx, relation.To)
-		if err != nil {
-			return err
-		}
+func (m *Manager) SearchNodes(ctx context.Context, query string) (*models.KnowledgeGraph, error) {
+	return m.store.SearchNodes(ctx, query)
+}
 
-		if fromEntity != nil && toEntity != nil {
-			_, err := tx.Exec("DELETE FROM relations WHERE from_entity_id = $1 AND to_entity_id = $2 AND relation_type = $3",
-				fromEntity.ID, toEntity.ID, relation.RelationType)
-			if err != nil {
-				return err
-			}
-		}
+// ReadGraphPage returns one page of the graph and whether more pages follow.
+func (m *Manager) ReadGraphPage(ctx context.Context, page Page) (*models.KnowledgeGraph, bool, error) {
+	if page.Limit <= 0 {
+		return nil, false, invalidArgumentf("page limit must be positive")
 	}
-
-	return tx.Commit()
+	return m.store.ReadGraphPage(ctx, page)
 }
 
-func (m *Manager) ReadGraph() (*models.KnowledgeGraph, error) {
-	// Get entities with observations
-	rows, err := m.db.Query(`
-        SELECT e.name, e.entity_type,
-               COALESCE(array_agg(o.content ORDER BY o.created_at) FILTER (WHERE o.content IS NOT NULL), ARRAY[]::text[]) as observations
-        FROM entities e
-        LEFT JOIN observations o ON e.id = o.entity_id
-        GROUP BY e.id, e.name, e.entity_type
-        ORDER BY e.name
-    `)
-	if err != nil {
-		return nil, err
+// SearchNodesPage returns one page of SearchNodes results and whether more
+// pages follow.
+func (m *Manager) SearchNodesPage(ctx context.Context, query string, page Page) (*models.KnowledgeGraph, bool, error) {
+	if page.Limit <= 0 {
+		return nil, false, invalidArgumentf("page limit must be positive")
 	}
-	defer rows.Close()
+	return m.store.SearchNodesPage(ctx, query, page)
+}
 
-	var entities []models.Entity
-	for rows.Next() {
-		var entity models.Entity
-		var observations pq.StringArray
+func (m *Manager) OpenNodes(ctx context.Context, names []string) (*models.KnowledgeGraph, error) {
+	return m.store.OpenNodes(ctx, names)
+}
 
-		err := rows.Scan(&entity.Name, &entity.EntityType, &observations)
-		if err != nil {
-			return nil, err
-		}
+// Complete returns up to limit distinct values of field that start with
+// prefix, ignoring case, ranked by recency or popularity.
+func (m *Manager) Complete(ctx context.Context, field CompletionField, prefix string, limit int) ([]string, error) {
+	return m.store.Complete(ctx, field, prefix, limit)
+}
 
-		entity.Observations = []string(observations)
-		entities = append(entities, entity)
+// Neighborhood returns the entities within query.Depth hops of the named
+// entities, expanding along relations in query.Direction, together with the
+// relations among them and each entity's distance from the nearest named
+// entity. Names that do not exist are ignored.
+func (m *Manager) Neighborhood(ctx context.Context, query NeighborhoodQuery) (*models.Neighborhood, error) {
+	if query.Direction == "" {
+		query.Direction = DirectionBoth
 	}
-
-	// Get relations
-	relationRows, err := m.db.Query(`
-        SELECT ef.name as from_name, et.name as to_name, r.relation_type
-        FROM relations r
-        JOIN entities ef ON r.from_entity_id = ef.id
-        JOIN entities et ON r.to_entity_id = et.id
-        ORDER BY ef.name, et.name
-    `)
-	if err != nil {
+	if err := checkDirection(query.Direction); err != nil {
 		return nil, err
 	}
-	defer relationRows.Close()
-
-	var relations []models.Relation
-	for relationRows.Next() {
-		var relation models.Relation
-		err := relationRows.Scan(&relation.From, &relation.To, &relation.RelationType)
-		if err != nil {
-			return nil, err
-		}
-		relations = append(relations, relation)
+	if query.Depth < 0 || query.Depth > maxNeighborhoodDepth {
+		return nil, invalidArgumentf("depth must be between 0 and %d", maxNeighborhoodDepth)
 	}
-
-	return &models.KnowledgeGraph{
-		Entities:  entities,
-		Relations: relations,
-	}, nil
+	return m.store.Neighborhood(ctx, query)
 }
 
-func (m *Manager) SearchNodes(query string) (*models.KnowledgeGraph, error) {
-	rows, err := m.db.Query(`
-        SELECT DISTINCT e.name, e.entity_type,
-               COALESCE(array_agg(o.content ORDER BY o.created_at) FILTER (WHERE o.content IS NOT NULL), ARRAY[]::text[]) as observations
-        FROM entities e
-        LEFT JOIN observations o ON e.id = o.entity_id
-        WHERE e.name ILIKE $1
-           OR e.entity_type ILIKE $1
-           OR to_tsvector('english', e.name) @@ plainto_tsquery('english', $2)
-           OR EXISTS (
-             SELECT 1 FROM observations obs 
-             WHERE obs.entity_id = e.id 
-             AND (obs.content ILIKE $1 OR to_tsvector('english', obs.content) @@ plainto_tsquery('english', $2))
-           )
-        GROUP BY e.id, e.name, e.entity_type
-        ORDER BY e.name
-    `, "%"+query+"%", query)
+// OpenEntity returns the named entity together with every relation that
+// starts or ends at it.
+func (m *Manager) OpenEntity(ctx context.Context, name string) (*models.Entity, []models.Relation, error) {
+	graph, err := m.store.OpenNodes(ctx, []string{name})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer rows.Close()
-
-	var entities []models.Entity
-	var entityNames []string
-
-	for rows.Next() {
-		var entity models.Entity
-		var observations pq.StringArray
-
-		err := rows.Scan(&entity.Name, &entity.EntityType, &observations)
-		if err != nil {
-			return nil, err
-		}
-
-		entity.Observations = []string(observations)
-		entities = append(entities, entity)
-		entityNames = append(entityNames, entity.Name)
+	if len(graph.Entities) == 0 {
+		return nil, nil, notFoundf("entity with name %s not found", name)
 	}
 
-	if len(entityNames) == 0 {
-		return &models.KnowledgeGraph{Entities: []models.Entity{}, Relations: []models.Relation{}}, nil
-	}
-
-	// Get relations between found entities
-	relationRows, err := m.db.Query(`
-        SELECT ef.name as from_name, et.name as to_name, r.relation_type
-        FROM relations r
-        JOIN entities ef ON r.from_entity_id = ef.id
-        JOIN entities et ON r.to_entity_id = et.id
-        WHERE ef.name = ANY($1) AND et.name = ANY($1)
-        ORDER BY ef.name, et.name
-    `, pq.Array(entityNames))
+	relations, err := m.store.Adjacent(ctx, []string{name}, DirectionBoth, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer relationRows.Close()
-
-	var relations []models.Relation
-	for relationRows.Next() {
-		var relation models.Relation
-		err := relationRows.Scan(&relation.From, &relation.To, &relation.RelationType)
-		if err != nil {
-			return nil, err
-		}
-		relations = append(relations, relation)
+	if relations == nil {
+		relations = []models.Relation{}
 	}
 
-	return &models.KnowledgeGraph{
-		Entities:  entities,
-		Relations: relations,
-	}, nil
+	return &graph.Entities[0], relations, nil
 }
 
-func (m *Manager) OpenNodes(names []string) (*models.KnowledgeGraph, error) {
-	if len(names) == 0 {
-		return &models.KnowledgeGraph{Entities: []models.Entity{}, Relations: []models.Relation{}}, nil
-	}
-
-	rows, err := m.db.Query(`
-        SELECT e.name, e.entity_type,
-               COALESCE(array_agg(o.content ORDER BY o.created_at) FILTER (WHERE o.content IS NOT NULL), ARRAY[]::text[]) as observations
-        FROM entities e
-        LEFT JOIN observations o ON e.id = o.entity_id
-        WHERE e.name = ANY($1)
-        GROUP BY e.id, e.name, e.entity_type
-        ORDER BY e.name
-    `, pq.Array(names))
+// EntitiesOfType returns the entities of one type and the relations among them.
+func (m *Manager) EntitiesOfType(ctx context.Context, entityType string) (*models.KnowledgeGraph, error) {
+	full, err := m.store.ReadGraph(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var entities []models.Entity
-	for rows.Next() {
-		var entity models.Entity
-		var observations pq.StringArray
 
-		err := rows.Scan(&entity.Name, &entity.EntityType, &observations)
-		if err != nil {
-			return nil, err
+	graph := &models.KnowledgeGraph{Entities: []models.Entity{}, Relations: []models.Relation{}}
+	included := make(map[string]bool)
+	for _, entity := range full.Entities {
+		if entity.EntityType == entityType {
+			graph.Entities = append(graph.Entities, entity)
+			included[entity.Name] = true
 		}
-
-		entity.Observations = []string(observations)
-		entities = append(entities, entity)
 	}
-
-	relationRows, err := m.db.Query(`
-        SELECT ef.name as from_name, et.name as to_name, r.relation_type
-        FROM relations r
-        JOIN entities ef ON r.from_entity_id = ef.id
-        JOIN entities et ON r.to_entity_id = et.id
-        WHERE ef.name = ANY($1) AND et.name = ANY($1)
-        ORDER BY ef.name, et.name
-    `, pq.Array(names))
-	if err != nil {
-		return nil, err
-	}
-	defer relationRows.Close()
-
-	var relations []models.Relation
-	for relationRows.Next() {
-		var relation models.Relation
-		err := relationRows.Scan(&relation.From, &relation.To, &relation.RelationType)
-		if err != nil {
-			return nil, err
+	for _, relation := range full.Relations {
+		if included[relation.From] && included[relation.To] {
+			graph.Relations = append(graph.Relations, relation)
 		}
-		relations = append(relations, relation)
 	}
 
-	return &models.KnowledgeGraph{
-		Entities:  entities,
-		Relations: relations,
-	}, nil
+	return graph, nil
 }
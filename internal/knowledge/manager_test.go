@@ -0,0 +1,134 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"mcp-compose-memory/internal/models"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// acquaintances is a graph in which alice knows bob and bob knows carol;
+// tests add dave, who knows nobody
+var acquaintances = []models.Relation{
+	{From: "alice", To: "bob", RelationType: "knows"},
+	{From: "bob", To: "carol", RelationType: "knows"},
+}
+
+func TestManagerDeleteEntitiesEvent(t *testing.T) {
+	tests := []struct {
+		name        string
+		delete      []string
+		wantUpdated []string
+	}{
+		{"middle of a chain", []string{"bob"}, []string{"alice", "carol"}},
+		{"both ends of a relation", []string{"alice", "bob"}, []string{"carol"}},
+		{"no relations", []string{"dave"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, acquaintances, "dave")
+
+			var events []ChangeEvent
+			m.OnChange(func(event ChangeEvent) { events = append(events, event) })
+
+			if err := m.DeleteEntities(context.Background(), tt.delete); err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != 1 {
+				t.Fatalf("got %d events, want 1", len(events))
+			}
+
+			updated := events[0].Updated
+			sort.Strings(updated)
+			if !reflect.DeepEqual(updated, tt.wantUpdated) {
+				t.Errorf("Updated = %v, want %v", updated, tt.wantUpdated)
+			}
+			if !reflect.DeepEqual(events[0].Deleted, tt.delete) {
+				t.Errorf("Deleted = %v, want %v", events[0].Deleted, tt.delete)
+			}
+		})
+	}
+}
+
+func TestManagerOpenEntity(t *testing.T) {
+	tests := []struct {
+		name          string
+		entity        string
+		wantRelations []models.Relation
+		wantNotFound  bool
+	}{
+		{"incoming and outgoing", "bob", []models.Relation{
+			{From: "alice", To: "bob", RelationType: "knows"},
+			{From: "bob", To: "carol", RelationType: "knows"},
+		}, false},
+		{"outgoing only", "alice", []models.Relation{
+			{From: "alice", To: "bob", RelationType: "knows"},
+		}, false},
+		{"no relations", "dave", []models.Relation{}, false},
+		{"missing", "erin", nil, true},
+	}
+
+	m := newTestManager(t, acquaintances, "dave")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entity, relations, err := m.OpenEntity(context.Background(), tt.entity)
+			if tt.wantNotFound {
+				if !errors.Is(err, ErrNotFound) {
+					t.Fatalf("err = %v, want a not found error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entity.Name != tt.entity {
+				t.Errorf("entity = %s, want %s", entity.Name, tt.entity)
+			}
+			if !reflect.DeepEqual(relations, tt.wantRelations) {
+				t.Errorf("relations = %v, want %v", relations, tt.wantRelations)
+			}
+		})
+	}
+}
+
+func TestManagerInvalidArguments(t *testing.T) {
+	m := newTestManager(t, acquaintances, "dave")
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{"neighborhood direction", func() error {
+			_, err := m.Neighborhood(ctx, NeighborhoodQuery{Names: []string{"alice"}, Depth: 1, Direction: "sideways"})
+			return err
+		}},
+		{"neighborhood depth", func() error {
+			_, err := m.Neighborhood(ctx, NeighborhoodQuery{Names: []string{"alice"}, Depth: maxNeighborhoodDepth + 1})
+			return err
+		}},
+		{"path length", func() error {
+			_, err := m.FindPaths(ctx, PathQuery{From: "alice", To: "bob", MaxPaths: 1})
+			return err
+		}},
+		{"page limit", func() error {
+			_, _, err := m.ReadGraphPage(ctx, Page{})
+			return err
+		}},
+		{"completion field", func() error {
+			_, err := m.Complete(ctx, CompletionField(99), "", 10)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); !errors.Is(err, ErrInvalidArgument) {
+				t.Errorf("err = %v, want ErrInvalidArgument", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,663 @@
+package knowledge
+
+import (
+	"context"
+	"mcp-compose-memory/internal/models"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// MemoryStore is an in-memory implementation of Store. It is safe for
+// concurrent use and keeps nothing across restarts, which makes it suitable
+// for tests and ephemeral sessions. Writes run to completion under the lock,
+// so they only check their context before starting; reads check it as they
+// scan the graph.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	entities  map[string]*models.Entity
+	relations []models.Relation
+
+	// changed records when each entity was last written, as a logical
+	// clock, so completions can rank entities by recency
+	clock   uint64
+	changed map[string]uint64
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// scanBatch is how many entities a read scans between checks of its
+// context and reports of its progress
+const scanBatch = 1000
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entities: make(map[string]*models.Entity),
+		changed:  make(map[string]uint64),
+	}
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func copyEntity(entity *models.Entity) models.Entity {
+	c := *entity
+	c.Observations = append([]string{}, entity.Observations...)
+	return c
+}
+
+// touch marks an entity as just written. Callers must hold s.mu.
+func (s *MemoryStore) touch(name string) {
+	s.clock++
+	s.changed[name] = s.clock
+}
+
+func (s *MemoryStore) hasRelation(relation models.Relation) bool {
+	for _, r := range s.relations {
+		if r.From == relation.From && r.To == relation.To && r.RelationType == relation.RelationType {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryStore) CreateEntities(ctx context.Context, entities []models.Entity) ([]models.Entity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var newEntities []models.Entity
+
+	for _, entity := range entities {
+		if _, exists := s.entities[entity.Name]; exists {
+			continue
+		}
+
+		s.entities[entity.Name] = &models.Entity{
+			Name:         entity.Name,
+			EntityType:   entity.EntityType,
+			Observations: append([]string{}, entity.Observations...),
+		}
+		s.touch(entity.Name)
+		newEntities = append(newEntities, entity)
+	}
+
+	return newEntities, nil
+}
+
+func (s *MemoryStore) CreateRelations(ctx context.Context, relations []models.Relation) ([]models.Relation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var newRelations []models.Relation
+
+	for _, relation := range relations {
+		fromExists, toExists := s.entities[relation.From] != nil, s.entities[relation.To] != nil
+		if !fromExists || !toExists {
+			logSkippedRelation(ctx, relation, fromExists, toExists)
+			continue
+		}
+
+		if !s.hasRelation(relation) {
+			s.relations = append(s.relations, models.Relation{
+				From:         relation.From,
+				To:           relation.To,
+				RelationType: relation.RelationType,
+			})
+			newRelations = append(newRelations, relation)
+		}
+	}
+
+	return newRelations, nil
+}
+
+func (s *MemoryStore) AddObservations(ctx context.Context, observations []models.ObservationAddition) ([]models.ObservationResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Validate every entity first so a failed call leaves the graph
+	// untouched, matching the transactional SQL stores.
+	for _, obs := range observations {
+		if s.entities[obs.EntityName] == nil {
+			return nil, notFoundf("entity with name %s not found", obs.EntityName)
+		}
+	}
+
+	var results []models.ObservationResult
+
+	for _, obs := range observations {
+		entity := s.entities[obs.EntityName]
+		existingObservations := append([]string{}, entity.Observations...)
+
+		var addedObservations []string
+		for _, content := range obs.Contents {
+			found := false
+			for _, existing := range existingObservations {
+				if existing == content {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				entity.Observations = append(entity.Observations, content)
+				addedObservations = append(addedObservations, content)
+			}
+		}
+		if len(addedObservations) > 0 {
+			s.touch(obs.EntityName)
+		}
+
+		results = append(results, models.ObservationResult{
+			EntityName:        obs.EntityName,
+			AddedObservations: addedObservations,
+		})
+	}
+
+	return results, nil
+}
+
+func (s *MemoryStore) DeleteEntities(ctx context.Context, entityNames []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	deleted := make(map[string]bool)
+	for _, name := range entityNames {
+		if _, exists := s.entities[name]; exists {
+			delete(s.entities, name)
+			delete(s.changed, name)
+			deleted[name] = true
+		}
+	}
+
+	// Cascade to relations touching a deleted entity
+	relations := s.relations[:0]
+	for _, r := range s.relations {
+		if !deleted[r.From] && !deleted[r.To] {
+			relations = append(relations, r)
+		}
+	}
+	s.relations = relations
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteObservations(ctx context.Context, deletions []models.ObservationDeletion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, deletion := range deletions {
+		entity := s.entities[deletion.EntityName]
+		if entity == nil {
+			continue
+		}
+
+		remove := make(map[string]bool)
+		for _, observation := range deletion.Observations {
+			remove[observation] = true
+		}
+
+		observations := entity.Observations[:0]
+		for _, observation := range entity.Observations {
+			if !remove[observation] {
+				observations = append(observations, observation)
+			}
+		}
+		entity.Observations = observations
+		s.touch(deletion.EntityName)
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteRelations(ctx context.Context, relations []models.Relation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, relation := range relations {
+		kept := s.relations[:0]
+		for _, r := range s.relations {
+			if r.From != relation.From || r.To != relation.To || r.RelationType != relation.RelationType {
+				kept = append(kept, r)
+			}
+		}
+		s.relations = kept
+	}
+
+	return nil
+}
+
+// scanEntities calls fn for every entity, stopping early if ctx is done.
+// Callers must hold s.mu.
+func (s *MemoryStore) scanEntities(ctx context.Context, fn func(*models.Entity)) error {
+	done := 0
+	for _, entity := range s.entities {
+		if done%scanBatch == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if done > 0 {
+				reportProgress(ctx, done, len(s.entities))
+			}
+		}
+		fn(entity)
+		done++
+	}
+	return ctx.Err()
+}
+
+// graphOf builds a KnowledgeGraph from the entities accepted by include and
+// the relations whose endpoints are both included, ordered like the SQL
+// stores. Callers must hold s.mu.
+func (s *MemoryStore) graphOf(ctx context.Context, include func(*models.Entity) bool) (*models.KnowledgeGraph, error) {
+	var entities []models.Entity
+	included := make(map[string]bool)
+
+	err := s.scanEntities(ctx, func(entity *models.Entity) {
+		if include(entity) {
+			entities = append(entities, copyEntity(entity))
+			included[entity.Name] = true
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entities, func(i, j int) bool { return entities[i].Name < entities[j].Name })
+
+	var relations []models.Relation
+	for _, r := range s.relations {
+		if included[r.From] && included[r.To] {
+			relations = append(relations, r)
+		}
+	}
+	sortRelations(relations)
+
+	return &models.KnowledgeGraph{
+		Entities:  entities,
+		Relations: relations,
+	}, nil
+}
+
+func sortRelations(relations []models.Relation) {
+	sort.SliceStable(relations, func(i, j int) bool {
+		if relations[i].From != relations[j].From {
+			return relations[i].From < relations[j].From
+		}
+		return relations[i].To < relations[j].To
+	})
+}
+
+func (s *MemoryStore) ReadGraph(ctx context.Context) (*models.KnowledgeGraph, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.graphOf(ctx, func(*models.Entity) bool { return true })
+}
+
+// matchesQuery approximates the search of the SQL stores: a
+// case-insensitive substring match on the whole query, or every query term
+// appearing in the same name or observation, either as a substring or as a
+// word with the same stem.
+func matchesQuery(entity *models.Entity, query string) bool {
+	needle := strings.ToLower(query)
+	terms := strings.Fields(needle)
+
+	var stems []string
+	for _, word := range strings.FieldsFunc(needle, isWordBreak) {
+		stems = append(stems, stem(word))
+	}
+
+	matches := func(text string) bool {
+		text = strings.ToLower(text)
+		if strings.Contains(text, needle) {
+			return true
+		}
+		if len(terms) == 0 {
+			return false
+		}
+
+		contained := true
+		for _, term := range terms {
+			if !strings.Contains(text, term) {
+				contained = false
+				break
+			}
+		}
+		if contained {
+			return true
+		}
+
+		words := make(map[string]bool)
+		for _, word := range strings.FieldsFunc(text, isWordBreak) {
+			words[stem(word)] = true
+		}
+		for _, s := range stems {
+			if !words[s] {
+				return false
+			}
+		}
+		return len(stems) > 0
+	}
+
+	if matches(entity.Name) || strings.Contains(strings.ToLower(entity.EntityType), needle) {
+		return true
+	}
+	for _, observation := range entity.Observations {
+		if matches(observation) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordBreak(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// stem strips the common English inflections from a lowercase word, so that
+// "runs", "running" and "run" compare equal. It is far cruder than the
+// Porter stemmer of the SQL stores, but agrees with it on plurals and the
+// -ed and -ing forms of regular words.
+func stem(word string) string {
+	if len(word) <= 3 {
+		return word
+	}
+
+	switch {
+	case strings.HasSuffix(word, "ies"):
+		word = strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "sses"):
+		word = strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "ss"):
+	case strings.HasSuffix(word, "s"):
+		word = strings.TrimSuffix(word, "s")
+	case strings.HasSuffix(word, "ing") && len(word) > 5:
+		word = undouble(strings.TrimSuffix(word, "ing"))
+	case strings.HasSuffix(word, "ed") && len(word) > 4:
+		word = undouble(strings.TrimSuffix(word, "ed"))
+	}
+
+	// A silent e is dropped, so that "notes", "noted" and "note" agree
+	if len(word) > 3 && strings.HasSuffix(word, "e") {
+		word = strings.TrimSuffix(word, "e")
+	}
+	return word
+}
+
+// undouble drops the doubled final consonant left by a stripped suffix, as
+// in "stopp" from "stopped"
+func undouble(word string) string {
+	n := len(word)
+	if n >= 2 && word[n-1] == word[n-2] && !strings.ContainsRune("aeiouylsz", rune(word[n-1])) {
+		return word[:n-1]
+	}
+	return word
+}
+
+func (s *MemoryStore) SearchNodes(ctx context.Context, query string) (*models.KnowledgeGraph, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	graph, err := s.graphOf(ctx, func(entity *models.Entity) bool { return matchesQuery(entity, query) })
+	if err != nil {
+		return nil, err
+	}
+	if len(graph.Entities) == 0 {
+		return &models.KnowledgeGraph{Entities: []models.Entity{}, Relations: []models.Relation{}}, nil
+	}
+	return graph, nil
+}
+
+func (s *MemoryStore) OpenNodes(ctx context.Context, names []string) (*models.KnowledgeGraph, error) {
+	if len(names) == 0 {
+		return &models.KnowledgeGraph{Entities: []models.Entity{}, Relations: []models.Relation{}}, nil
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.graphOf(ctx, func(entity *models.Entity) bool { return wanted[entity.Name] })
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, field CompletionField, prefix string, limit int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lowerPrefix := strings.ToLower(prefix)
+	matches := func(value string) bool {
+		return strings.HasPrefix(strings.ToLower(value), lowerPrefix)
+	}
+
+	var values []string
+	switch field {
+	case CompleteEntityName:
+		for name := range s.entities {
+			if matches(name) {
+				values = append(values, name)
+			}
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if s.changed[values[i]] != s.changed[values[j]] {
+				return s.changed[values[i]] > s.changed[values[j]]
+			}
+			return values[i] < values[j]
+		})
+	case CompleteEntityType, CompleteRelationType:
+		counts := make(map[string]int)
+		if field == CompleteEntityType {
+			for _, entity := range s.entities {
+				counts[entity.EntityType]++
+			}
+		} else {
+			for _, relation := range s.relations {
+				counts[relation.RelationType]++
+			}
+		}
+		for value := range counts {
+			if matches(value) {
+				values = append(values, value)
+			}
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if counts[values[i]] != counts[values[j]] {
+				return counts[values[i]] > counts[values[j]]
+			}
+			return values[i] < values[j]
+		})
+	default:
+		return nil, invalidArgumentf("unknown completion field %d", field)
+	}
+
+	if len(values) > limit {
+		values = values[:limit]
+	}
+	return values, nil
+}
+
+// pageOf returns one page of the entities accepted by include, in name
+// order, with the relations that start in the page and end at an included
+// entity. Callers must hold s.mu.
+func (s *MemoryStore) pageOf(ctx context.Context, include func(*models.Entity) bool, page Page) (*models.KnowledgeGraph, bool, error) {
+	var names []string
+	included := make(map[string]bool)
+	err := s.scanEntities(ctx, func(entity *models.Entity) {
+		if include(entity) {
+			included[entity.Name] = true
+			if entity.Name > page.After {
+				names = append(names, entity.Name)
+			}
+		}
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	sort.Strings(names)
+
+	// Like the SQL stores, take one entity more than the page holds to tell
+	// whether another page follows
+	if len(names) > page.Limit+1 {
+		names = names[:page.Limit+1]
+	}
+	var entities []models.Entity
+	for _, name := range names {
+		entities = append(entities, copyEntity(s.entities[name]))
+	}
+	entities, names, more := trimPage(entities, page.Limit)
+
+	inPage := make(map[string]bool)
+	for _, name := range names {
+		inPage[name] = true
+	}
+
+	var relations []models.Relation
+	for _, r := range s.relations {
+		if inPage[r.From] && included[r.To] {
+			relations = append(relations, r)
+		}
+	}
+	sortRelations(relations)
+
+	return &models.KnowledgeGraph{Entities: entities, Relations: relations}, more, nil
+}
+
+func (s *MemoryStore) ReadGraphPage(ctx context.Context, page Page) (*models.KnowledgeGraph, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.pageOf(ctx, func(*models.Entity) bool { return true }, page)
+}
+
+func (s *MemoryStore) SearchNodesPage(ctx context.Context, query string, page Page) (*models.KnowledgeGraph, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	graph, more, err := s.pageOf(ctx, func(entity *models.Entity) bool { return matchesQuery(entity, query) }, page)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(graph.Entities) == 0 {
+		return &models.KnowledgeGraph{Entities: []models.Entity{}, Relations: []models.Relation{}}, false, nil
+	}
+	return graph, more, nil
+}
+
+func (s *MemoryStore) Neighborhood(ctx context.Context, query NeighborhoodQuery) (*models.Neighborhood, error) {
+	follow := make(map[string]bool)
+	for _, relationType := range query.RelationTypes {
+		follow[relationType] = true
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	depths := make(map[string]int)
+	var frontier []string
+	visit := func(name string, depth int) {
+		if _, seen := depths[name]; seen || s.entities[name] == nil {
+			return
+		}
+		depths[name] = depth
+		frontier = append(frontier, name)
+	}
+
+	for _, name := range query.Names {
+		visit(name, 0)
+	}
+
+	// Breadth first, so each entity is reached at its least depth. A whole
+	// depth is expanded before stopping at the size limit, so that the
+	// entities kept are the same as in the SQL stores.
+	for depth := 1; depth <= query.Depth && len(frontier) > 0 && len(depths) <= maxNeighborhoodNodes; depth++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		current := make(map[string]bool)
+		for _, name := range frontier {
+			current[name] = true
+		}
+		frontier = nil
+
+		for _, relation := range s.relations {
+			if len(follow) > 0 && !follow[relation.RelationType] {
+				continue
+			}
+			if query.Direction != DirectionIn && current[relation.From] {
+				visit(relation.To, depth)
+			}
+			if query.Direction != DirectionOut && current[relation.To] {
+				visit(relation.From, depth)
+			}
+		}
+	}
+
+	_, truncated := limitDepths(depths)
+	graph, err := s.graphOf(ctx, func(entity *models.Entity) bool {
+		_, ok := depths[entity.Name]
+		return ok
+	})
+	if err != nil {
+		return nil, err
+	}
+	return neighborhoodOf(graph, depths, truncated, query), nil
+}
+
+func (s *MemoryStore) Adjacent(ctx context.Context, names []string, direction Direction, relationTypes []string) ([]models.Relation, error) {
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		wanted[name] = true
+	}
+	follow := make(map[string]bool)
+	for _, relationType := range relationTypes {
+		follow[relationType] = true
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var relations []models.Relation
+	for _, relation := range s.relations {
+		if len(follow) > 0 && !follow[relation.RelationType] {
+			continue
+		}
+		// Relations to entities that do not exist are not followed
+		if s.entities[relation.From] == nil || s.entities[relation.To] == nil {
+			continue
+		}
+		if (direction != DirectionIn && wanted[relation.From]) || (direction != DirectionOut && wanted[relation.To]) {
+			relations = append(relations, relation)
+		}
+	}
+	sortRelations(relations)
+
+	return relations, nil
+}
@@ -0,0 +1,133 @@
+package knowledge
+
+import (
+	"database/sql"
+	"mcp-compose-memory/internal/models"
+	"sort"
+)
+
+// Direction selects the relations a neighborhood expands along
+type Direction string
+
+const (
+	// DirectionOut follows relations from their source to their target
+	DirectionOut Direction = "out"
+	// DirectionIn follows relations from their target back to their source
+	DirectionIn Direction = "in"
+	// DirectionBoth follows relations either way
+	DirectionBoth Direction = "both"
+)
+
+// maxNeighborhoodDepth is the most relations a neighborhood may extend
+// from the named entities
+const maxNeighborhoodDepth = 10
+
+// maxNeighborhoodNodes caps the entities a neighborhood may hold, so that a
+// deep neighborhood in a densely connected graph cannot load all of it. It
+// is a variable so that tests can lower it.
+var maxNeighborhoodNodes = 10000
+
+// checkDirection rejects a direction other than the three defined
+func checkDirection(direction Direction) error {
+	switch direction {
+	case DirectionOut, DirectionIn, DirectionBoth:
+		return nil
+	}
+	return invalidArgumentf("unknown direction %q, expected in, out or both", direction)
+}
+
+// NeighborhoodQuery selects the entities within Depth hops of the named
+// entities for Store.Neighborhood
+type NeighborhoodQuery struct {
+	Names     []string
+	Depth     int
+	Direction Direction
+	// RelationTypes limits the relations followed and returned to these
+	// types; empty means every type
+	RelationTypes []string
+}
+
+// neighborhoodEdges selects every relation as a src -> dst step in the
+// direction a neighborhood expands, for the recursive queries of the SQL
+// stores
+func neighborhoodEdges(direction Direction) string {
+	out := "SELECT from_entity_id AS src, to_entity_id AS dst, relation_type FROM relations"
+	in := "SELECT to_entity_id AS src, from_entity_id AS dst, relation_type FROM relations"
+
+	switch direction {
+	case DirectionOut:
+		return out
+	case DirectionIn:
+		return in
+	default:
+		return out + " UNION ALL " + in
+	}
+}
+
+// scanDepths reads the name and depth of each entity a neighborhood query
+// reached
+func scanDepths(rows *sql.Rows) (map[string]int, error) {
+	depths := make(map[string]int)
+	for rows.Next() {
+		var name string
+		var depth int
+		if err := rows.Scan(&name, &depth); err != nil {
+			return nil, err
+		}
+		depths[name] = depth
+	}
+	return depths, rows.Err()
+}
+
+// limitDepths keeps the maxNeighborhoodNodes entities nearest to the named
+// ones, breaking ties by name, and reports whether any were left out
+func limitDepths(depths map[string]int) ([]string, bool) {
+	names := make([]string, 0, len(depths))
+	for name := range depths {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if depths[names[i]] != depths[names[j]] {
+			return depths[names[i]] < depths[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	if len(names) <= maxNeighborhoodNodes {
+		return names, false
+	}
+	for _, name := range names[maxNeighborhoodNodes:] {
+		delete(depths, name)
+	}
+	return names[:maxNeighborhoodNodes], true
+}
+
+// neighborhoodOf annotates the graph of the entities reached by a
+// neighborhood query with their depths. Relations of other types than the
+// query follows are dropped, and entities are ordered by depth, then name.
+func neighborhoodOf(graph *models.KnowledgeGraph, depths map[string]int, truncated bool, query NeighborhoodQuery) *models.Neighborhood {
+	if len(query.RelationTypes) > 0 {
+		follow := make(map[string]bool)
+		for _, relationType := range query.RelationTypes {
+			follow[relationType] = true
+		}
+
+		relations := []models.Relation{}
+		for _, relation := range graph.Relations {
+			if follow[relation.RelationType] {
+				relations = append(relations, relation)
+			}
+		}
+		graph.Relations = relations
+	}
+
+	sort.SliceStable(graph.Entities, func(i, j int) bool {
+		a, b := graph.Entities[i], graph.Entities[j]
+		if depths[a.Name] != depths[b.Name] {
+			return depths[a.Name] < depths[b.Name]
+		}
+		return a.Name < b.Name
+	})
+
+	return &models.Neighborhood{KnowledgeGraph: *graph, Depths: depths, Truncated: truncated}
+}
@@ -0,0 +1,127 @@
+package knowledge
+
+import (
+	"context"
+	"mcp-compose-memory/internal/models"
+	"reflect"
+	"testing"
+)
+
+// neighborhoodGraph is a chain a -> b -> c -> d with a cycle back from c to
+// a, a relation of another type from b to x and one into a from e
+var neighborhoodGraph = []models.Relation{
+	{From: "a", To: "b", RelationType: "knows"},
+	{From: "b", To: "c", RelationType: "knows"},
+	{From: "c", To: "d", RelationType: "knows"},
+	{From: "c", To: "a", RelationType: "knows"},
+	{From: "b", To: "x", RelationType: "owns"},
+	{From: "e", To: "a", RelationType: "knows"},
+}
+
+func TestStoreNeighborhood(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      NeighborhoodQuery
+		wantDepths map[string]int
+	}{
+		{
+			name:       "depth 0",
+			query:      NeighborhoodQuery{Names: []string{"a"}, Depth: 0, Direction: DirectionBoth},
+			wantDepths: map[string]int{"a": 0},
+		},
+		{
+			name:       "out",
+			query:      NeighborhoodQuery{Names: []string{"a"}, Depth: 2, Direction: DirectionOut},
+			wantDepths: map[string]int{"a": 0, "b": 1, "c": 2, "x": 2},
+		},
+		{
+			name:       "in",
+			query:      NeighborhoodQuery{Names: []string{"a"}, Depth: 2, Direction: DirectionIn},
+			wantDepths: map[string]int{"a": 0, "c": 1, "e": 1, "b": 2},
+		},
+		{
+			name:       "both",
+			query:      NeighborhoodQuery{Names: []string{"a"}, Depth: 1, Direction: DirectionBoth},
+			wantDepths: map[string]int{"a": 0, "b": 1, "c": 1, "e": 1},
+		},
+		{
+			name:       "cycle ends with depth",
+			query:      NeighborhoodQuery{Names: []string{"a"}, Depth: 10, Direction: DirectionOut},
+			wantDepths: map[string]int{"a": 0, "b": 1, "c": 2, "x": 2, "d": 3},
+		},
+		{
+			name:       "relation type",
+			query:      NeighborhoodQuery{Names: []string{"b"}, Depth: 2, Direction: DirectionOut, RelationTypes: []string{"owns"}},
+			wantDepths: map[string]int{"b": 0, "x": 1},
+		},
+		{
+			name:       "several names",
+			query:      NeighborhoodQuery{Names: []string{"d", "e"}, Depth: 1, Direction: DirectionBoth},
+			wantDepths: map[string]int{"d": 0, "e": 0, "c": 1, "a": 1},
+		},
+		{
+			name:       "missing name",
+			query:      NeighborhoodQuery{Names: []string{"nobody"}, Depth: 1, Direction: DirectionBoth},
+			wantDepths: map[string]int{},
+		},
+	}
+
+	// The memory store is the reference the others must match
+	reference := NewMemoryStore()
+	seedStore(t, reference, neighborhoodGraph)
+
+	forEachStore(t, func(t *testing.T, store Store) {
+		seedStore(t, store, neighborhoodGraph)
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ctx := context.Background()
+
+				got, err := store.Neighborhood(ctx, tt.query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got.Depths, tt.wantDepths) {
+					t.Errorf("depths = %v, want %v", got.Depths, tt.wantDepths)
+				}
+
+				want, err := reference.Neighborhood(ctx, tt.query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sortRelations(got.Relations)
+				sortRelations(want.Relations)
+				if !reflect.DeepEqual(entityNames(got.Entities), entityNames(want.Entities)) {
+					t.Errorf("entities = %v, want %v", entityNames(got.Entities), entityNames(want.Entities))
+				}
+				if len(got.Relations) != len(want.Relations) || (len(want.Relations) > 0 && !reflect.DeepEqual(got.Relations, want.Relations)) {
+					t.Errorf("relations = %v, want %v", got.Relations, want.Relations)
+				}
+			})
+		}
+	})
+}
+
+func TestStoreNeighborhoodLimit(t *testing.T) {
+	defer func(limit int) { maxNeighborhoodNodes = limit }(maxNeighborhoodNodes)
+	maxNeighborhoodNodes = 4
+
+	forEachStore(t, func(t *testing.T, store Store) {
+		seedStore(t, store, neighborhoodGraph)
+
+		got, err := store.Neighborhood(context.Background(), NeighborhoodQuery{Names: []string{"a"}, Depth: 3, Direction: DirectionBoth})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Truncated {
+			t.Error("neighborhood over the limit was not truncated")
+		}
+		// The nearest entities are kept, the nearer ones first by name
+		if want := []string{"a", "b", "c", "e"}; !reflect.DeepEqual(entityNames(got.Entities), want) {
+			t.Errorf("entities = %v, want %v", entityNames(got.Entities), want)
+		}
+		if len(got.Depths) != 4 {
+			t.Errorf("depths = %v, want only the entities kept", got.Depths)
+		}
+	})
+}
@@ -0,0 +1,293 @@
+package knowledge
+
+import (
+	"context"
+	"mcp-compose-memory/internal/models"
+	"sort"
+)
+
+const (
+	// maxPathNodes caps the entities a path search may explore, so that a
+	// search across a large, densely connected graph cannot load all of it
+	maxPathNodes = 10000
+
+	// maxPathSteps caps the steps taken while enumerating simple paths,
+	// whose number can grow exponentially with their length
+	maxPathSteps = 1000000
+)
+
+// PathQuery selects the paths between two entities for Manager.FindPaths
+type PathQuery struct {
+	From      string
+	To        string
+	Direction Direction
+	// RelationTypes limits the relations followed to these types; empty
+	// means every type
+	RelationTypes []string
+	// MaxLength is the most relations a path may have
+	MaxLength int
+	// AllPaths also requests every simple path of up to MaxLength relations
+	AllPaths bool
+	// MaxPaths caps the number of paths of each kind returned
+	MaxPaths int
+}
+
+// pathStep is one relation leading away from an entity during a search
+type pathStep struct {
+	to       string
+	relation models.Relation
+}
+
+// pathSearch finds paths by breadth-first search, loading the relations of
+// each level of the search from the store as it goes
+type pathSearch struct {
+	store     Store
+	query     PathQuery
+	adjacency map[string][]pathStep
+	truncated bool
+}
+
+// FindPaths returns the shortest paths from query.From to query.To of at
+// most query.MaxLength relations and, if query.AllPaths is set, every
+// simple path of that length. The result is marked truncated if the search
+// reached its limits, in which case paths may be missing.
+func (m *Manager) FindPaths(ctx context.Context, query PathQuery) (*models.FindPathsResult, error) {
+	if query.Direction == "" {
+		query.Direction = DirectionBoth
+	}
+	if err := checkDirection(query.Direction); err != nil {
+		return nil, err
+	}
+	if query.MaxLength < 1 || query.MaxPaths < 1 {
+		return nil, invalidArgumentf("maxLength and maxPaths must be positive")
+	}
+
+	graph, err := m.store.OpenNodes(ctx, []string{query.From, query.To})
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool)
+	for _, entity := range graph.Entities {
+		found[entity.Name] = true
+	}
+	for _, name := range []string{query.From, query.To} {
+		if !found[name] {
+			return nil, notFoundf("entity with name %s not found", name)
+		}
+	}
+
+	search := &pathSearch{store: m.store, query: query, adjacency: make(map[string][]pathStep)}
+
+	shortest, err := search.shortestPaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.FindPathsResult{ShortestPaths: shortest}
+
+	if query.AllPaths {
+		result.Paths, err = search.simplePaths(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result.Truncated = search.truncated
+	return result, nil
+}
+
+// expand loads the relations leading away from names that are not loaded yet
+func (s *pathSearch) expand(ctx context.Context, names []string) error {
+	var missing []string
+	loading := make(map[string]bool)
+	for _, name := range names {
+		if _, ok := s.adjacency[name]; !ok && !loading[name] {
+			missing = append(missing, name)
+			loading[name] = true
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	relations, err := s.store.Adjacent(ctx, missing, s.query.Direction, s.query.RelationTypes)
+	if err != nil {
+		return err
+	}
+
+	for _, relation := range relations {
+		if s.query.Direction != DirectionIn && loading[relation.From] {
+			s.adjacency[relation.From] = append(s.adjacency[relation.From], pathStep{to: relation.To, relation: relation})
+		}
+		if s.query.Direction != DirectionOut && loading[relation.To] && relation.From != relation.To {
+			s.adjacency[relation.To] = append(s.adjacency[relation.To], pathStep{to: relation.From, relation: relation})
+		}
+	}
+
+	for _, name := range missing {
+		steps := s.adjacency[name]
+		if steps == nil {
+			// Remember that the entity has no relations to follow
+			steps = []pathStep{}
+		}
+		s.adjacency[name] = steps
+		sort.SliceStable(steps, func(i, j int) bool {
+			if steps[i].to != steps[j].to {
+				return steps[i].to < steps[j].to
+			}
+			return steps[i].relation.RelationType < steps[j].relation.RelationType
+		})
+	}
+	return nil
+}
+
+// shortestPaths searches outward from the start one level at a time until
+// it reaches the end. Unless every simple path is wanted too, it stops at
+// the level of the end, having loaded only the relations it needed.
+func (s *pathSearch) shortestPaths(ctx context.Context) ([]models.Path, error) {
+	from, to := s.query.From, s.query.To
+
+	depths := map[string]int{from: 0}
+	// previous holds the steps back towards the start on a shortest path
+	previous := make(map[string][]pathStep)
+
+	frontier := []string{from}
+	for depth := 0; depth < s.query.MaxLength && len(frontier) > 0; depth++ {
+		if _, reached := depths[to]; reached && !s.query.AllPaths {
+			break
+		}
+		if err := s.expand(ctx, frontier); err != nil {
+			return nil, err
+		}
+
+		var next []string
+		for _, name := range frontier {
+			for _, step := range s.adjacency[name] {
+				d, seen := depths[step.to]
+				if !seen {
+					if len(depths) >= maxPathNodes {
+						s.truncated = true
+						continue
+					}
+					d = depth + 1
+					depths[step.to] = d
+					next = append(next, step.to)
+				}
+				if d == depth+1 {
+					previous[step.to] = append(previous[step.to], pathStep{to: name, relation: step.relation})
+				}
+			}
+		}
+		frontier = next
+	}
+
+	if _, reached := depths[to]; !reached {
+		return []models.Path{}, nil
+	}
+
+	// Walk back from the end along every shortest route
+	paths := []models.Path{}
+	var back func(name string, names []string, relations []models.Relation) bool
+	back = func(name string, names []string, relations []models.Relation) bool {
+		names = append(names, name)
+		if name == from {
+			if len(paths) >= s.query.MaxPaths {
+				s.truncated = true
+				return false
+			}
+			paths = append(paths, reversedPath(names, relations))
+			return true
+		}
+		for _, step := range previous[name] {
+			if !back(step.to, names, append(relations, step.relation)) {
+				return false
+			}
+		}
+		return true
+	}
+	back(to, nil, nil)
+
+	return paths, nil
+}
+
+// simplePaths enumerates every path from the start to the end that visits
+// no entity twice, using the relations loaded by shortestPaths
+func (s *pathSearch) simplePaths(ctx context.Context) ([]models.Path, error) {
+	from, to := s.query.From, s.query.To
+
+	paths := []models.Path{}
+	onPath := map[string]bool{from: true}
+	names := []string{from}
+	var relations []models.Relation
+	steps := 0
+
+	var walk func(name string) (bool, error)
+	walk = func(name string) (bool, error) {
+		if name == to {
+			if len(paths) >= s.query.MaxPaths {
+				s.truncated = true
+				return false, nil
+			}
+			paths = append(paths, models.Path{
+				Entities:  append([]string{}, names...),
+				Relations: append([]models.Relation{}, relations...),
+			})
+			return true, nil
+		}
+		if len(relations) == s.query.MaxLength {
+			return true, nil
+		}
+
+		for _, step := range s.adjacency[name] {
+			if onPath[step.to] {
+				continue
+			}
+
+			steps++
+			if steps > maxPathSteps {
+				s.truncated = true
+				return false, nil
+			}
+			if steps%1000 == 0 {
+				if err := ctx.Err(); err != nil {
+					return false, err
+				}
+			}
+
+			onPath[step.to] = true
+			names = append(names, step.to)
+			relations = append(relations, step.relation)
+
+			more, err := walk(step.to)
+
+			onPath[step.to] = false
+			names = names[:len(names)-1]
+			relations = relations[:len(relations)-1]
+
+			if !more || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	if _, err := walk(from); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// reversedPath builds a path from the entities and relations collected
+// while walking it from its end
+func reversedPath(names []string, relations []models.Relation) models.Path {
+	path := models.Path{
+		Entities:  make([]string, len(names)),
+		Relations: make([]models.Relation, len(relations)),
+	}
+	for i, name := range names {
+		path.Entities[len(names)-1-i] = name
+	}
+	for i, relation := range relations {
+		path.Relations[len(relations)-1-i] = relation
+	}
+	return path
+}
@@ -0,0 +1,146 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"mcp-compose-memory/internal/models"
+	"reflect"
+	"testing"
+)
+
+func pathEntities(paths []models.Path) [][]string {
+	entities := [][]string{}
+	for _, path := range paths {
+		entities = append(entities, path.Entities)
+	}
+	return entities
+}
+
+func TestFindPaths(t *testing.T) {
+	// a reaches d through b or c, and through b then c
+	m := newTestManager(t, []models.Relation{
+		{From: "a", To: "b", RelationType: "next"},
+		{From: "a", To: "c", RelationType: "next"},
+		{From: "b", To: "c", RelationType: "shortcut"},
+		{From: "b", To: "d", RelationType: "next"},
+		{From: "c", To: "d", RelationType: "next"},
+	})
+
+	tests := []struct {
+		name          string
+		query         PathQuery
+		wantShortest  [][]string
+		wantPaths     [][]string
+		wantTruncated bool
+	}{
+		{
+			name:         "shortest",
+			query:        PathQuery{From: "a", To: "d", MaxLength: 6, MaxPaths: 100},
+			wantShortest: [][]string{{"a", "b", "d"}, {"a", "c", "d"}},
+		},
+		{
+			name:          "shortest capped by MaxPaths",
+			query:         PathQuery{From: "a", To: "d", MaxLength: 6, MaxPaths: 1},
+			wantShortest:  [][]string{{"a", "b", "d"}},
+			wantTruncated: true,
+		},
+		{
+			name:         "beyond MaxLength",
+			query:        PathQuery{From: "a", To: "d", MaxLength: 1, MaxPaths: 100},
+			wantShortest: [][]string{},
+		},
+		{
+			name:         "against the relations",
+			query:        PathQuery{From: "d", To: "a", Direction: DirectionOut, MaxLength: 6, MaxPaths: 100},
+			wantShortest: [][]string{},
+		},
+		{
+			name:         "incoming relations",
+			query:        PathQuery{From: "d", To: "a", Direction: DirectionIn, MaxLength: 6, MaxPaths: 100},
+			wantShortest: [][]string{{"d", "b", "a"}, {"d", "c", "a"}},
+		},
+		{
+			name:         "relation types",
+			query:        PathQuery{From: "b", To: "c", RelationTypes: []string{"next"}, MaxLength: 6, MaxPaths: 100},
+			wantShortest: [][]string{{"b", "a", "c"}, {"b", "d", "c"}},
+		},
+		{
+			name:         "all paths",
+			query:        PathQuery{From: "a", To: "d", Direction: DirectionOut, MaxLength: 6, AllPaths: true, MaxPaths: 100},
+			wantShortest: [][]string{{"a", "b", "d"}, {"a", "c", "d"}},
+			wantPaths:    [][]string{{"a", "b", "c", "d"}, {"a", "b", "d"}, {"a", "c", "d"}},
+		},
+		{
+			name:          "all paths capped by MaxPaths",
+			query:         PathQuery{From: "a", To: "d", Direction: DirectionOut, MaxLength: 6, AllPaths: true, MaxPaths: 2},
+			wantShortest:  [][]string{{"a", "b", "d"}, {"a", "c", "d"}},
+			wantPaths:     [][]string{{"a", "b", "c", "d"}, {"a", "b", "d"}},
+			wantTruncated: true,
+		},
+		{
+			name:         "all paths within MaxLength",
+			query:        PathQuery{From: "a", To: "d", Direction: DirectionOut, MaxLength: 2, AllPaths: true, MaxPaths: 100},
+			wantShortest: [][]string{{"a", "b", "d"}, {"a", "c", "d"}},
+			wantPaths:    [][]string{{"a", "b", "d"}, {"a", "c", "d"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := m.FindPaths(context.Background(), tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := pathEntities(result.ShortestPaths); !reflect.DeepEqual(got, tt.wantShortest) {
+				t.Errorf("shortest paths = %v, want %v", got, tt.wantShortest)
+			}
+			if tt.query.AllPaths {
+				if got := pathEntities(result.Paths); !reflect.DeepEqual(got, tt.wantPaths) {
+					t.Errorf("paths = %v, want %v", got, tt.wantPaths)
+				}
+			}
+			if result.Truncated != tt.wantTruncated {
+				t.Errorf("truncated = %v, want %v", result.Truncated, tt.wantTruncated)
+			}
+			for _, path := range result.ShortestPaths {
+				if len(path.Relations) != len(path.Entities)-1 {
+					t.Errorf("path %v has %d relations", path.Entities, len(path.Relations))
+				}
+			}
+		})
+	}
+}
+
+func TestFindPathsMissingEntity(t *testing.T) {
+	m := newTestManager(t, []models.Relation{{From: "a", To: "b", RelationType: "next"}})
+
+	_, err := m.FindPaths(context.Background(), PathQuery{From: "a", To: "z", MaxLength: 6, MaxPaths: 100})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFindPathsStepLimit(t *testing.T) {
+	// A complete graph has more simple paths than the search may enumerate
+	var relations []models.Relation
+	names := []string{"n00", "n01", "n02", "n03", "n04", "n05", "n06", "n07", "n08", "n09", "n10", "n11"}
+	for _, from := range names {
+		for _, to := range names {
+			if from != to {
+				relations = append(relations, models.Relation{From: from, To: to, RelationType: "link"})
+			}
+		}
+	}
+	m := newTestManager(t, relations)
+
+	result, err := m.FindPaths(context.Background(), PathQuery{
+		From: "n00", To: "n11", Direction: DirectionOut, MaxLength: 10, AllPaths: true, MaxPaths: 1000000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Truncated {
+		t.Errorf("search of %d paths was not truncated", len(result.Paths))
+	}
+}
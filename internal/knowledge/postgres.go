@@ -0,0 +1,771 @@
+package knowledge
+
+import (
+	"context"
+	"database/sql"
+	"mcp-compose-memory/internal/models"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// PostgresStore is the PostgreSQL implementation of Store.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+var _ Store = (*PostgresStore)(nil)
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *PostgresStore) getEntityByName(ctx context.Context, tx *sql.Tx, name string) (*models.Entity, error) {
+	var entity models.Entity
+	err := tx.QueryRowContext(ctx, "SELECT id, name, entity_type FROM entities WHERE name = $1", name).
+		Scan(&entity.ID, &entity.Name, &entity.EntityType)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entity, nil
+}
+
+func (s *PostgresStore) getEntityObservations(ctx context.Context, tx *sql.Tx, entityID int) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT content FROM observations WHERE entity_id = $1 ORDER BY created_at", entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var observations []string
+	for rows.Next() {
+		var content string
+		if err := rows.Scan(&content); err != nil {
+			return nil, err
+		}
+		observations = append(observations, content)
+	}
+
+	return observations, rows.Err()
+}
+
+func (s *PostgresStore) CreateEntities(ctx context.Context, entities []models.Entity) ([]models.Entity, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var rows rowCount
+	var newEntities []models.Entity
+
+	for i, entity := range entities {
+		existingEntity, err := s.getEntityByName(ctx, tx, entity.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if existingEntity == nil {
+			var entityID int
+			err := tx.QueryRowContext(ctx, "INSERT INTO entities (name, entity_type) VALUES ($1, $2) RETURNING id",
+				entity.Name, entity.EntityType).Scan(&entityID)
+			if err != nil {
+				return nil, err
+			}
+			rows++
+
+			for _, observation := range entity.Observations {
+				if err := rows.add(tx.ExecContext(ctx, "INSERT INTO observations (entity_id, content) VALUES ($1, $2)",
+					entityID, observation)); err != nil {
+					return nil, err
+				}
+			}
+
+			newEntities = append(newEntities, entity)
+		}
+		reportProgress(ctx, i+1, len(entities))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	rows.log(ctx, "CreateEntities")
+
+	return newEntities, nil
+}
+
+func (s *PostgresStore) CreateRelations(ctx context.Context, relations []models.Relation) ([]models.Relation, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var rows rowCount
+	var newRelations []models.Relation
+
+	for i, relation := range relations {
+		fromEntity, err := s.getEntityByName(ctx, tx, relation.From)
+		if err != nil {
+			return nil, err
+		}
+		toEntity, err := s.getEntityByName(ctx, tx, relation.To)
+		if err != nil {
+			return nil, err
+		}
+
+		if fromEntity == nil || toEntity == nil {
+			logSkippedRelation(ctx, relation, fromEntity != nil, toEntity != nil)
+			continue
+		}
+
+		var exists bool
+		err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM relations WHERE from_entity_id = $1 AND to_entity_id = $2 AND relation_type = $3)",
+			fromEntity.ID, toEntity.ID, relation.RelationType).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			if err := rows.add(tx.ExecContext(ctx, "INSERT INTO relations (from_entity_id, to_entity_id, relation_type) VALUES ($1, $2, $3)",
+				fromEntity.ID, toEntity.ID, relation.RelationType)); err != nil {
+				return nil, err
+			}
+			newRelations = append(newRelations, relation)
+		}
+		reportProgress(ctx, i+1, len(relations))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	rows.log(ctx, "CreateRelations")
+
+	return newRelations, nil
+}
+
+func (s *PostgresStore) AddObservations(ctx context.Context, observations []models.ObservationAddition) ([]models.ObservationResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var rows rowCount
+	var results []models.ObservationResult
+
+	for i, obs := range observations {
+		entity, err := s.getEntityByName(ctx, tx, obs.EntityName)
+		if err != nil {
+			return nil, err
+		}
+		if entity == nil {
+			return nil, notFoundf("entity with name %s not found", obs.EntityName)
+		}
+
+		existingObservations, err := s.getEntityObservations(ctx, tx, entity.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		var addedObservations []string
+		for _, content := range obs.Contents {
+			found := false
+			for _, existing := range existingObservations {
+				if existing == content {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				if err := rows.add(tx.ExecContext(ctx, "INSERT INTO observations (entity_id, content) VALUES ($1, $2)",
+					entity.ID, content)); err != nil {
+					return nil, err
+				}
+				addedObservations = append(addedObservations, content)
+			}
+		}
+
+		results = append(results, models.ObservationResult{
+			EntityName:        obs.EntityName,
+			AddedObservations: addedObservations,
+		})
+		reportProgress(ctx, i+1, len(observations))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	rows.log(ctx, "AddObservations")
+
+	return results, nil
+}
+
+func (s *PostgresStore) DeleteEntities(ctx context.Context, entityNames []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var rows rowCount
+
+	for i, name := range entityNames {
+		if err := rows.add(tx.ExecContext(ctx, "DELETE FROM entities WHERE name = $1", name)); err != nil {
+			return err
+		}
+		reportProgress(ctx, i+1, len(entityNames))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	rows.log(ctx, "DeleteEntities")
+	return nil
+}
+
+func (s *PostgresStore) DeleteObservations(ctx context.Context, deletions []models.ObservationDeletion) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var rows rowCount
+
+	for i, deletion := range deletions {
+		entity, err := s.getEntityByName(ctx, tx, deletion.EntityName)
+		if err != nil {
+			return err
+		}
+		if entity != nil {
+			for _, observation := range deletion.Observations {
+				if err := rows.add(tx.ExecContext(ctx, "DELETE FROM observations WHERE entity_id = $1 AND content = $2",
+					entity.ID, observation)); err != nil {
+					return err
+				}
+			}
+		}
+		reportProgress(ctx, i+1, len(deletions))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	rows.log(ctx, "DeleteObservations")
+	return nil
+}
+
+func (s *PostgresStore) DeleteRelations(ctx context.Context, relations []models.Relation) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var rows rowCount
+
+	for i, relation := range relations {
+		fromEntity, err := s.getEntityByName(ctx, tx, relation.From)
+		if err != nil {
+			return err
+		}
+		toEntity, err := s.getEntityByName(ctx, tx, relation.To)
+		if err != nil {
+			return err
+		}
+
+		if fromEntity != nil && toEntity != nil {
+			if err := rows.add(tx.ExecContext(ctx, "DELETE FROM relations WHERE from_entity_id = $1 AND to_entity_id = $2 AND relation_type = $3",
+				fromEntity.ID, toEntity.ID, relation.RelationType)); err != nil {
+				return err
+			}
+		}
+		reportProgress(ctx, i+1, len(relations))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	rows.log(ctx, "DeleteRelations")
+	return nil
+}
+
+func (s *PostgresStore) ReadGraph(ctx context.Context) (*models.KnowledgeGraph, error) {
+	// Get entities with observations
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT e.name, e.entity_type,
+               COALESCE(array_agg(o.content ORDER BY o.created_at) FILTER (WHERE o.content IS NOT NULL), ARRAY[]::text[]) as observations
+        FROM entities e
+        LEFT JOIN observations o ON e.id = o.entity_id
+        GROUP BY e.id, e.name, e.entity_type
+        ORDER BY e.name
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entities []models.Entity
+	for rows.Next() {
+		var entity models.Entity
+		var observations pq.StringArray
+
+		err := rows.Scan(&entity.Name, &entity.EntityType, &observations)
+		if err != nil {
+			return nil, err
+		}
+
+		entity.Observations = []string(observations)
+		entities = append(entities, entity)
+		reportProgress(ctx, len(entities), 0)
+	}
+
+	// Get relations
+	relationRows, err := s.db.QueryContext(ctx, `
+        SELECT ef.name as from_name, et.name as to_name, r.relation_type
+        FROM relations r
+        JOIN entities ef ON r.from_entity_id = ef.id
+        JOIN entities et ON r.to_entity_id = et.id
+        ORDER BY ef.name, et.name
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer relationRows.Close()
+
+	var relations []models.Relation
+	for relationRows.Next() {
+		var relation models.Relation
+		err := relationRows.Scan(&relation.From, &relation.To, &relation.RelationType)
+		if err != nil {
+			return nil, err
+		}
+		relations = append(relations, relation)
+	}
+
+	return &models.KnowledgeGraph{
+		Entities:  entities,
+		Relations: relations,
+	}, nil
+}
+
+func (s *PostgresStore) SearchNodes(ctx context.Context, query string) (*models.KnowledgeGraph, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT DISTINCT e.name, e.entity_type,
+               COALESCE(array_agg(o.content ORDER BY o.created_at) FILTER (WHERE o.content IS NOT NULL), ARRAY[]::text[]) as observations
+        FROM entities e
+        LEFT JOIN observations o ON e.id = o.entity_id
+        WHERE e.name ILIKE $1
+           OR e.entity_type ILIKE $1
+           OR to_tsvector('english', e.name) @@ plainto_tsquery('english', $2)
+           OR EXISTS (
+             SELECT 1 FROM observations obs 
+             WHERE obs.entity_id = e.id 
+             AND (obs.content ILIKE $1 OR to_tsvector('english', obs.content) @@ plainto_tsquery('english', $2))
+           )
+        GROUP BY e.id, e.name, e.entity_type
+        ORDER BY e.name
+    `, "%"+query+"%", query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entities []models.Entity
+	var entityNames []string
+
+	for rows.Next() {
+		var entity models.Entity
+		var observations pq.StringArray
+
+		err := rows.Scan(&entity.Name, &entity.EntityType, &observations)
+		if err != nil {
+			return nil, err
+		}
+
+		entity.Observations = []string(observations)
+		entities = append(entities, entity)
+		reportProgress(ctx, len(entities), 0)
+		entityNames = append(entityNames, entity.Name)
+	}
+
+	if len(entityNames) == 0 {
+		return &models.KnowledgeGraph{Entities: []models.Entity{}, Relations: []models.Relation{}}, nil
+	}
+
+	// Get relations between found entities
+	relationRows, err := s.db.QueryContext(ctx, `
+        SELECT ef.name as from_name, et.name as to_name, r.relation_type
+        FROM relations r
+        JOIN entities ef ON r.from_entity_id = ef.id
+        JOIN entities et ON r.to_entity_id = et.id
+        WHERE ef.name = ANY($1) AND et.name = ANY($1)
+        ORDER BY ef.name, et.name
+    `, pq.Array(entityNames))
+	if err != nil {
+		return nil, err
+	}
+	defer relationRows.Close()
+
+	var relations []models.Relation
+	for relationRows.Next() {
+		var relation models.Relation
+		err := relationRows.Scan(&relation.From, &relation.To, &relation.RelationType)
+		if err != nil {
+			return nil, err
+		}
+		relations = append(relations, relation)
+	}
+
+	return &models.KnowledgeGraph{
+		Entities:  entities,
+		Relations: relations,
+	}, nil
+}
+
+func (s *PostgresStore) OpenNodes(ctx context.Context, names []string) (*models.KnowledgeGraph, error) {
+	if len(names) == 0 {
+		return &models.KnowledgeGraph{Entities: []models.Entity{}, Relations: []models.Relation{}}, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT e.name, e.entity_type,
+               COALESCE(array_agg(o.content ORDER BY o.created_at) FILTER (WHERE o.content IS NOT NULL), ARRAY[]::text[]) as observations
+        FROM entities e
+        LEFT JOIN observations o ON e.id = o.entity_id
+        WHERE e.name = ANY($1)
+        GROUP BY e.id, e.name, e.entity_type
+        ORDER BY e.name
+    `, pq.Array(names))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entities []models.Entity
+	for rows.Next() {
+		var entity models.Entity
+		var observations pq.StringArray
+
+		err := rows.Scan(&entity.Name, &entity.EntityType, &observations)
+		if err != nil {
+			return nil, err
+		}
+
+		entity.Observations = []string(observations)
+		entities = append(entities, entity)
+		reportProgress(ctx, len(entities), 0)
+	}
+
+	relationRows, err := s.db.QueryContext(ctx, `
+        SELECT ef.name as from_name, et.name as to_name, r.relation_type
+        FROM relations r
+        JOIN entities ef ON r.from_entity_id = ef.id
+        JOIN entities et ON r.to_entity_id = et.id
+        WHERE ef.name = ANY($1) AND et.name = ANY($1)
+        ORDER BY ef.name, et.name
+    `, pq.Array(names))
+	if err != nil {
+		return nil, err
+	}
+	defer relationRows.Close()
+
+	var relations []models.Relation
+	for relationRows.Next() {
+		var relation models.Relation
+		err := relationRows.Scan(&relation.From, &relation.To, &relation.RelationType)
+		if err != nil {
+			return nil, err
+		}
+		relations = append(relations, relation)
+	}
+
+	return &models.KnowledgeGraph{
+		Entities:  entities,
+		Relations: relations,
+	}, nil
+}
+
+// likePrefix escapes prefix for use as a LIKE pattern matching values that
+// start with it
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+}
+
+func (s *PostgresStore) Complete(ctx context.Context, field CompletionField, prefix string, limit int) ([]string, error) {
+	var query string
+	switch field {
+	case CompleteEntityName:
+		query = `
+            SELECT e.name
+            FROM entities e
+            WHERE e.name ILIKE $1
+            ORDER BY GREATEST(e.updated_at, (SELECT MAX(o.created_at) FROM observations o WHERE o.entity_id = e.id)) DESC, e.name
+            LIMIT $2
+        `
+	case CompleteEntityType:
+		query = `
+            SELECT entity_type
+            FROM entities
+            WHERE entity_type ILIKE $1
+            GROUP BY entity_type
+            ORDER BY COUNT(*) DESC, entity_type
+            LIMIT $2
+        `
+	case CompleteRelationType:
+		query = `
+            SELECT relation_type
+            FROM relations
+            WHERE relation_type ILIKE $1
+            GROUP BY relation_type
+            ORDER BY COUNT(*) DESC, relation_type
+            LIMIT $2
+        `
+	default:
+		return nil, invalidArgumentf("unknown completion field %d", field)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, likePrefix(prefix), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
+
+// postgresSearchMatches selects the ids of entities matching a search, with
+// the ILIKE pattern as $1 and the full-text query as $2.
+const postgresSearchMatches = `
+        SELECT e.id FROM entities e
+        WHERE e.name ILIKE $1
+           OR e.entity_type ILIKE $1
+           OR to_tsvector('english', e.name) @@ plainto_tsquery('english', $2)
+           OR EXISTS (
+             SELECT 1 FROM observations obs
+             WHERE obs.entity_id = e.id
+             AND (obs.content ILIKE $1 OR to_tsvector('english', obs.content) @@ plainto_tsquery('english', $2))
+           )
+`
+
+func scanPostgresEntities(ctx context.Context, rows *sql.Rows) ([]models.Entity, error) {
+	defer rows.Close()
+
+	var entities []models.Entity
+	for rows.Next() {
+		var entity models.Entity
+		var observations pq.StringArray
+
+		if err := rows.Scan(&entity.Name, &entity.EntityType, &observations); err != nil {
+			return nil, err
+		}
+
+		entity.Observations = []string(observations)
+		entities = append(entities, entity)
+		reportProgress(ctx, len(entities), 0)
+	}
+
+	return entities, rows.Err()
+}
+
+func scanPostgresRelations(rows *sql.Rows) ([]models.Relation, error) {
+	defer rows.Close()
+
+	var relations []models.Relation
+	for rows.Next() {
+		var relation models.Relation
+		if err := rows.Scan(&relation.From, &relation.To, &relation.RelationType); err != nil {
+			return nil, err
+		}
+		relations = append(relations, relation)
+	}
+
+	return relations, rows.Err()
+}
+
+func (s *PostgresStore) ReadGraphPage(ctx context.Context, page Page) (*models.KnowledgeGraph, bool, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT e.name, e.entity_type,
+               COALESCE(array_agg(o.content ORDER BY o.created_at) FILTER (WHERE o.content IS NOT NULL), ARRAY[]::text[]) as observations
+        FROM entities e
+        LEFT JOIN observations o ON e.id = o.entity_id
+        WHERE e.name > $1
+        GROUP BY e.id, e.name, e.entity_type
+        ORDER BY e.name
+        LIMIT $2
+    `, page.After, page.Limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	entities, err := scanPostgresEntities(ctx, rows)
+	if err != nil {
+		return nil, false, err
+	}
+
+	entities, names, more := trimPage(entities, page.Limit)
+
+	relationRows, err := s.db.QueryContext(ctx, `
+        SELECT ef.name as from_name, et.name as to_name, r.relation_type
+        FROM relations r
+        JOIN entities ef ON r.from_entity_id = ef.id
+        JOIN entities et ON r.to_entity_id = et.id
+        WHERE ef.name = ANY($1)
+        ORDER BY ef.name, et.name
+    `, pq.Array(names))
+	if err != nil {
+		return nil, false, err
+	}
+	relations, err := scanPostgresRelations(relationRows)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &models.KnowledgeGraph{Entities: entities, Relations: relations}, more, nil
+}
+
+func (s *PostgresStore) SearchNodesPage(ctx context.Context, query string, page Page) (*models.KnowledgeGraph, bool, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        WITH matched AS (`+postgresSearchMatches+`)
+        SELECT e.name, e.entity_type,
+               COALESCE(array_agg(o.content ORDER BY o.created_at) FILTER (WHERE o.content IS NOT NULL), ARRAY[]::text[]) as observations
+        FROM entities e
+        LEFT JOIN observations o ON e.id = o.entity_id
+        WHERE e.id IN (SELECT id FROM matched) AND e.name > $3
+        GROUP BY e.id, e.name, e.entity_type
+        ORDER BY e.name
+        LIMIT $4
+    `, "%"+query+"%", query, page.After, page.Limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	entities, err := scanPostgresEntities(ctx, rows)
+	if err != nil {
+		return nil, false, err
+	}
+
+	entities, names, more := trimPage(entities, page.Limit)
+	if len(entities) == 0 {
+		return &models.KnowledgeGraph{Entities: []models.Entity{}, Relations: []models.Relation{}}, false, nil
+	}
+
+	// Relations from this page to any matching entity
+	relationRows, err := s.db.QueryContext(ctx, `
+        WITH matched AS (`+postgresSearchMatches+`)
+        SELECT ef.name as from_name, et.name as to_name, r.relation_type
+        FROM relations r
+        JOIN entities ef ON r.from_entity_id = ef.id
+        JOIN entities et ON r.to_entity_id = et.id
+        WHERE ef.name = ANY($3) AND et.id IN (SELECT id FROM matched)
+        ORDER BY ef.name, et.name
+    `, "%"+query+"%", query, pq.Array(names))
+	if err != nil {
+		return nil, false, err
+	}
+	relations, err := scanPostgresRelations(relationRows)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &models.KnowledgeGraph{Entities: entities, Relations: relations}, more, nil
+}
+
+func (s *PostgresStore) Neighborhood(ctx context.Context, query NeighborhoodQuery) (*models.Neighborhood, error) {
+	// The walk visits each entity at most once per depth, so cycles end
+	// when the depth runs out
+	rows, err := s.db.QueryContext(ctx, `
+        WITH RECURSIVE edges AS (`+neighborhoodEdges(query.Direction)+`),
+        walk(id, depth) AS (
+            SELECT id, 0 FROM entities WHERE name = ANY($1)
+            UNION
+            SELECT edges.dst, walk.depth + 1
+            FROM walk
+            JOIN edges ON edges.src = walk.id
+            WHERE walk.depth < $2
+              AND (COALESCE(cardinality($3::text[]), 0) = 0 OR edges.relation_type = ANY($3))
+        )
+        SELECT e.name, MIN(walk.depth)
+        FROM walk
+        JOIN entities e ON e.id = walk.id
+        GROUP BY e.name
+        ORDER BY MIN(walk.depth), e.name
+        LIMIT $4
+    `, pq.Array(query.Names), query.Depth, pq.Array(query.RelationTypes), maxNeighborhoodNodes+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	depths, err := scanDepths(rows)
+	if err != nil {
+		return nil, err
+	}
+	names, truncated := limitDepths(depths)
+
+	graph, err := s.OpenNodes(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	return neighborhoodOf(graph, depths, truncated, query), nil
+}
+
+func (s *PostgresStore) Adjacent(ctx context.Context, names []string, direction Direction, relationTypes []string) ([]models.Relation, error) {
+	var ends string
+	switch direction {
+	case DirectionOut:
+		ends = "ef.name = ANY($1)"
+	case DirectionIn:
+		ends = "et.name = ANY($1)"
+	default:
+		ends = "(ef.name = ANY($1) OR et.name = ANY($1))"
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT ef.name as from_name, et.name as to_name, r.relation_type
+        FROM relations r
+        JOIN entities ef ON r.from_entity_id = ef.id
+        JOIN entities et ON r.to_entity_id = et.id
+        WHERE `+ends+`
+          AND (COALESCE(cardinality($2::text[]), 0) = 0 OR r.relation_type = ANY($2))
+        ORDER BY ef.name, et.name, r.relation_type
+    `, pq.Array(names), pq.Array(relationTypes))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var relations []models.Relation
+	for rows.Next() {
+		var relation models.Relation
+		if err := rows.Scan(&relation.From, &relation.To, &relation.RelationType); err != nil {
+			return nil, err
+		}
+		relations = append(relations, relation)
+	}
+
+	return relations, rows.Err()
+}
@@ -0,0 +1,24 @@
+package knowledge
+
+import "context"
+
+// ProgressFunc receives the progress of a long-running operation: done
+// items out of total, where total is zero if it is not known in advance.
+// It is called synchronously, so it must return quickly.
+type ProgressFunc func(done, total int)
+
+type progressKey struct{}
+
+// WithProgress returns a context under which Manager operations report
+// their progress to fn. The database stores report each item of a write
+// and each entity read; the in-memory stores report the entities scanned
+// by reads of large graphs and finish writes too quickly to report.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func reportProgress(ctx context.Context, done, total int) {
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok {
+		fn(done, total)
+	}
+}
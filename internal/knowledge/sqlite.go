@@ -0,0 +1,683 @@
+package knowledge
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"mcp-compose-memory/internal/models"
+	"strings"
+)
+
+// SQLiteStore is the embedded SQLite implementation of Store. It uses the
+// same schema as PostgresStore, with FTS5 tables standing in for the
+// tsvector indexes used by SearchNodes.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+var _ Store = (*SQLiteStore)(nil)
+
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{db: db}
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) getEntityByName(ctx context.Context, tx *sql.Tx, name string) (*models.Entity, error) {
+	var entity models.Entity
+	err := tx.QueryRowContext(ctx, "SELECT id, name, entity_type FROM entities WHERE name = ?", name).
+		Scan(&entity.ID, &entity.Name, &entity.EntityType)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entity, nil
+}
+
+func (s *SQLiteStore) getEntityObservations(ctx context.Context, tx *sql.Tx, entityID int) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT content FROM observations WHERE entity_id = ? ORDER BY created_at, id", entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var observations []string
+	for rows.Next() {
+		var content string
+		if err := rows.Scan(&content); err != nil {
+			return nil, err
+		}
+		observations = append(observations, content)
+	}
+
+	return observations, rows.Err()
+}
+
+func (s *SQLiteStore) CreateEntities(ctx context.Context, entities []models.Entity) ([]models.Entity, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var rows rowCount
+	var newEntities []models.Entity
+
+	for i, entity := range entities {
+		existingEntity, err := s.getEntityByName(ctx, tx, entity.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if existingEntity == nil {
+			res, err := tx.ExecContext(ctx, "INSERT INTO entities (name, entity_type) VALUES (?, ?)",
+				entity.Name, entity.EntityType)
+			if err != nil {
+				return nil, err
+			}
+			entityID, err := res.LastInsertId()
+			if err != nil {
+				return nil, err
+			}
+			rows++
+
+			for _, observation := range entity.Observations {
+				if err := rows.add(tx.ExecContext(ctx, "INSERT INTO observations (entity_id, content) VALUES (?, ?)",
+					entityID, observation)); err != nil {
+					return nil, err
+				}
+			}
+
+			newEntities = append(newEntities, entity)
+		}
+		reportProgress(ctx, i+1, len(entities))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	rows.log(ctx, "CreateEntities")
+
+	return newEntities, nil
+}
+
+func (s *SQLiteStore) CreateRelations(ctx context.Context, relations []models.Relation) ([]models.Relation, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var rows rowCount
+	var newRelations []models.Relation
+
+	for i, relation := range relations {
+		fromEntity, err := s.getEntityByName(ctx, tx, relation.From)
+		if err != nil {
+			return nil, err
+		}
+		toEntity, err := s.getEntityByName(ctx, tx, relation.To)
+		if err != nil {
+			return nil, err
+		}
+
+		if fromEntity == nil || toEntity == nil {
+			logSkippedRelation(ctx, relation, fromEntity != nil, toEntity != nil)
+			continue
+		}
+
+		res, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO relations (from_entity_id, to_entity_id, relation_type) VALUES (?, ?, ?)",
+			fromEntity.ID, toEntity.ID, relation.RelationType)
+		if err != nil {
+			return nil, err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if n > 0 {
+			rows += rowCount(n)
+			newRelations = append(newRelations, relation)
+		}
+		reportProgress(ctx, i+1, len(relations))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	rows.log(ctx, "CreateRelations")
+
+	return newRelations, nil
+}
+
+func (s *SQLiteStore) AddObservations(ctx context.Context, observations []models.ObservationAddition) ([]models.ObservationResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var rows rowCount
+	var results []models.ObservationResult
+
+	for i, obs := range observations {
+		entity, err := s.getEntityByName(ctx, tx, obs.EntityName)
+		if err != nil {
+			return nil, err
+		}
+		if entity == nil {
+			return nil, notFoundf("entity with name %s not found", obs.EntityName)
+		}
+
+		existingObservations, err := s.getEntityObservations(ctx, tx, entity.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		var addedObservations []string
+		for _, content := range obs.Contents {
+			found := false
+			for _, existing := range existingObservations {
+				if existing == content {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				if err := rows.add(tx.ExecContext(ctx, "INSERT INTO observations (entity_id, content) VALUES (?, ?)",
+					entity.ID, content)); err != nil {
+					return nil, err
+				}
+				addedObservations = append(addedObservations, content)
+			}
+		}
+
+		results = append(results, models.ObservationResult{
+			EntityName:        obs.EntityName,
+			AddedObservations: addedObservations,
+		})
+		reportProgress(ctx, i+1, len(observations))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	rows.log(ctx, "AddObservations")
+
+	return results, nil
+}
+
+func (s *SQLiteStore) DeleteEntities(ctx context.Context, entityNames []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var rows rowCount
+
+	for i, name := range entityNames {
+		if err := rows.add(tx.ExecContext(ctx, "DELETE FROM entities WHERE name = ?", name)); err != nil {
+			return err
+		}
+		reportProgress(ctx, i+1, len(entityNames))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	rows.log(ctx, "DeleteEntities")
+	return nil
+}
+
+func (s *SQLiteStore) DeleteObservations(ctx context.Context, deletions []models.ObservationDeletion) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var rows rowCount
+
+	for i, deletion := range deletions {
+		entity, err := s.getEntityByName(ctx, tx, deletion.EntityName)
+		if err != nil {
+			return err
+		}
+		if entity != nil {
+			for _, observation := range deletion.Observations {
+				if err := rows.add(tx.ExecContext(ctx, "DELETE FROM observations WHERE entity_id = ? AND content = ?",
+					entity.ID, observation)); err != nil {
+					return err
+				}
+			}
+		}
+		reportProgress(ctx, i+1, len(deletions))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	rows.log(ctx, "DeleteObservations")
+	return nil
+}
+
+func (s *SQLiteStore) DeleteRelations(ctx context.Context, relations []models.Relation) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var rows rowCount
+
+	for i, relation := range relations {
+		if err := rows.add(tx.ExecContext(ctx, `
+            DELETE FROM relations
+            WHERE from_entity_id = (SELECT id FROM entities WHERE name = ?)
+              AND to_entity_id = (SELECT id FROM entities WHERE name = ?)
+              AND relation_type = ?
+        `, relation.From, relation.To, relation.RelationType)); err != nil {
+			return err
+		}
+		reportProgress(ctx, i+1, len(relations))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	rows.log(ctx, "DeleteRelations")
+	return nil
+}
+
+// sqliteEntityColumns selects an entity together with its observations,
+// aggregated in insertion order into a JSON array.
+const sqliteEntityColumns = `
+        SELECT e.name, e.entity_type,
+               (SELECT json_group_array(content) FROM (
+                    SELECT content FROM observations o
+                    WHERE o.entity_id = e.id
+                    ORDER BY o.created_at, o.id
+               )) AS observations
+        FROM entities e
+`
+
+const sqliteRelationColumns = `
+        SELECT ef.name as from_name, et.name as to_name, r.relation_type
+        FROM relations r
+        JOIN entities ef ON r.from_entity_id = ef.id
+        JOIN entities et ON r.to_entity_id = et.id
+`
+
+func (s *SQLiteStore) queryEntities(ctx context.Context, query string, args ...interface{}) ([]models.Entity, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entities []models.Entity
+	for rows.Next() {
+		var entity models.Entity
+		var observations string
+
+		if err := rows.Scan(&entity.Name, &entity.EntityType, &observations); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(observations), &entity.Observations); err != nil {
+			return nil, err
+		}
+		entities = append(entities, entity)
+		reportProgress(ctx, len(entities), 0)
+	}
+
+	return entities, rows.Err()
+}
+
+func (s *SQLiteStore) queryRelations(ctx context.Context, query string, args ...interface{}) ([]models.Relation, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var relations []models.Relation
+	for rows.Next() {
+		var relation models.Relation
+		if err := rows.Scan(&relation.From, &relation.To, &relation.RelationType); err != nil {
+			return nil, err
+		}
+		relations = append(relations, relation)
+	}
+
+	return relations, rows.Err()
+}
+
+// relationsBetween returns the relations whose endpoints are both in names.
+func (s *SQLiteStore) relationsBetween(ctx context.Context, names []string) ([]models.Relation, error) {
+	namesJSON, err := json.Marshal(names)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.queryRelations(ctx, sqliteRelationColumns+`
+        WHERE ef.name IN (SELECT value FROM json_each(?1))
+          AND et.name IN (SELECT value FROM json_each(?1))
+        ORDER BY ef.name, et.name
+    `, string(namesJSON))
+}
+
+func (s *SQLiteStore) ReadGraph(ctx context.Context) (*models.KnowledgeGraph, error) {
+	entities, err := s.queryEntities(ctx, sqliteEntityColumns+" ORDER BY e.name")
+	if err != nil {
+		return nil, err
+	}
+
+	relations, err := s.queryRelations(ctx, sqliteRelationColumns+" ORDER BY ef.name, et.name")
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.KnowledgeGraph{
+		Entities:  entities,
+		Relations: relations,
+	}, nil
+}
+
+// ftsQuery turns free text into an FTS5 query matching all of its terms,
+// mirroring plainto_tsquery. Each term is quoted so that FTS5 operators in
+// the input are treated literally.
+func ftsQuery(query string) string {
+	var terms []string
+	for _, field := range strings.Fields(query) {
+		terms = append(terms, `"`+strings.ReplaceAll(field, `"`, `""`)+`"`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// sqliteSearchConditions returns the WHERE clause matching entities for a
+// search together with its arguments, bound as ?1 and, if there are terms
+// for FTS5, ?2.
+func sqliteSearchConditions(query string) (string, []interface{}) {
+	conditions := `
+        WHERE e.name LIKE ?1
+           OR e.entity_type LIKE ?1
+           OR EXISTS (SELECT 1 FROM observations obs WHERE obs.entity_id = e.id AND obs.content LIKE ?1)
+    `
+	args := []interface{}{"%" + query + "%"}
+
+	if match := ftsQuery(query); match != "" {
+		conditions += `
+           OR e.id IN (SELECT rowid FROM entities_fts WHERE entities_fts MATCH ?2)
+           OR e.id IN (
+             SELECT obs.entity_id FROM observations obs
+             WHERE obs.id IN (SELECT rowid FROM observations_fts WHERE observations_fts MATCH ?2)
+           )
+        `
+		args = append(args, match)
+	}
+
+	return conditions, args
+}
+
+func (s *SQLiteStore) SearchNodes(ctx context.Context, query string) (*models.KnowledgeGraph, error) {
+	conditions, args := sqliteSearchConditions(query)
+
+	entities, err := s.queryEntities(ctx, sqliteEntityColumns+conditions+" ORDER BY e.name", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entities) == 0 {
+		return &models.KnowledgeGraph{Entities: []models.Entity{}, Relations: []models.Relation{}}, nil
+	}
+
+	entityNames := make([]string, len(entities))
+	for i, entity := range entities {
+		entityNames[i] = entity.Name
+	}
+
+	// Get relations between found entities
+	relations, err := s.relationsBetween(ctx, entityNames)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.KnowledgeGraph{
+		Entities:  entities,
+		Relations: relations,
+	}, nil
+}
+
+func (s *SQLiteStore) OpenNodes(ctx context.Context, names []string) (*models.KnowledgeGraph, error) {
+	if len(names) == 0 {
+		return &models.KnowledgeGraph{Entities: []models.Entity{}, Relations: []models.Relation{}}, nil
+	}
+
+	namesJSON, err := json.Marshal(names)
+	if err != nil {
+		return nil, err
+	}
+
+	entities, err := s.queryEntities(ctx, sqliteEntityColumns+`
+        WHERE e.name IN (SELECT value FROM json_each(?))
+        ORDER BY e.name
+    `, string(namesJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	relations, err := s.relationsBetween(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.KnowledgeGraph{
+		Entities:  entities,
+		Relations: relations,
+	}, nil
+}
+
+func (s *SQLiteStore) Complete(ctx context.Context, field CompletionField, prefix string, limit int) ([]string, error) {
+	var query string
+	switch field {
+	case CompleteEntityName:
+		query = `
+            SELECT e.name
+            FROM entities e
+            WHERE e.name LIKE ? ESCAPE '\'
+            ORDER BY MAX(e.updated_at, COALESCE((SELECT MAX(o.created_at) FROM observations o WHERE o.entity_id = e.id), e.updated_at)) DESC,
+                     e.id DESC
+            LIMIT ?
+        `
+	case CompleteEntityType:
+		query = `
+            SELECT entity_type
+            FROM entities
+            WHERE entity_type LIKE ? ESCAPE '\'
+            GROUP BY entity_type
+            ORDER BY COUNT(*) DESC, entity_type
+            LIMIT ?
+        `
+	case CompleteRelationType:
+		query = `
+            SELECT relation_type
+            FROM relations
+            WHERE relation_type LIKE ? ESCAPE '\'
+            GROUP BY relation_type
+            ORDER BY COUNT(*) DESC, relation_type
+            LIMIT ?
+        `
+	default:
+		return nil, invalidArgumentf("unknown completion field %d", field)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, likePrefix(prefix), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
+
+func (s *SQLiteStore) ReadGraphPage(ctx context.Context, page Page) (*models.KnowledgeGraph, bool, error) {
+	entities, err := s.queryEntities(ctx, sqliteEntityColumns+`
+        WHERE e.name > ?
+        ORDER BY e.name
+        LIMIT ?
+    `, page.After, page.Limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+
+	entities, names, more := trimPage(entities, page.Limit)
+
+	namesJSON, err := json.Marshal(names)
+	if err != nil {
+		return nil, false, err
+	}
+
+	relations, err := s.queryRelations(ctx, sqliteRelationColumns+`
+        WHERE ef.name IN (SELECT value FROM json_each(?))
+        ORDER BY ef.name, et.name
+    `, string(namesJSON))
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &models.KnowledgeGraph{Entities: entities, Relations: relations}, more, nil
+}
+
+func (s *SQLiteStore) SearchNodesPage(ctx context.Context, query string, page Page) (*models.KnowledgeGraph, bool, error) {
+	conditions, args := sqliteSearchConditions(query)
+	matched := "WITH matched AS (SELECT e.id FROM entities e " + conditions + ")"
+	next := len(args) + 1
+
+	entities, err := s.queryEntities(ctx, matched+sqliteEntityColumns+fmt.Sprintf(`
+        WHERE e.id IN (SELECT id FROM matched) AND e.name > ?%d
+        ORDER BY e.name
+        LIMIT ?%d
+    `, next, next+1), append(args, page.After, page.Limit+1)...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	entities, names, more := trimPage(entities, page.Limit)
+	if len(entities) == 0 {
+		return &models.KnowledgeGraph{Entities: []models.Entity{}, Relations: []models.Relation{}}, false, nil
+	}
+
+	namesJSON, err := json.Marshal(names)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Relations from this page to any matching entity
+	relations, err := s.queryRelations(ctx, matched+sqliteRelationColumns+fmt.Sprintf(`
+        WHERE ef.name IN (SELECT value FROM json_each(?%d))
+          AND et.id IN (SELECT id FROM matched)
+        ORDER BY ef.name, et.name
+    `, next), append(args, string(namesJSON))...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &models.KnowledgeGraph{Entities: entities, Relations: relations}, more, nil
+}
+
+func (s *SQLiteStore) Neighborhood(ctx context.Context, query NeighborhoodQuery) (*models.Neighborhood, error) {
+	namesJSON, err := json.Marshal(query.Names)
+	if err != nil {
+		return nil, err
+	}
+	typesJSON, err := json.Marshal(append([]string{}, query.RelationTypes...))
+	if err != nil {
+		return nil, err
+	}
+
+	// The walk visits each entity at most once per depth, so cycles end
+	// when the depth runs out
+	rows, err := s.db.QueryContext(ctx, `
+        WITH RECURSIVE edges AS (`+neighborhoodEdges(query.Direction)+`),
+        walk(id, depth) AS (
+            SELECT id, 0 FROM entities WHERE name IN (SELECT value FROM json_each(?1))
+            UNION
+            SELECT edges.dst, walk.depth + 1
+            FROM walk
+            JOIN edges ON edges.src = walk.id
+            WHERE walk.depth < ?2
+              AND (json_array_length(?3) = 0 OR edges.relation_type IN (SELECT value FROM json_each(?3)))
+        )
+        SELECT e.name, MIN(walk.depth)
+        FROM walk
+        JOIN entities e ON e.id = walk.id
+        GROUP BY e.name
+        ORDER BY MIN(walk.depth), e.name
+        LIMIT ?4
+    `, string(namesJSON), query.Depth, string(typesJSON), maxNeighborhoodNodes+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	depths, err := scanDepths(rows)
+	if err != nil {
+		return nil, err
+	}
+	names, truncated := limitDepths(depths)
+
+	graph, err := s.OpenNodes(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	return neighborhoodOf(graph, depths, truncated, query), nil
+}
+
+func (s *SQLiteStore) Adjacent(ctx context.Context, names []string, direction Direction, relationTypes []string) ([]models.Relation, error) {
+	namesJSON, err := json.Marshal(names)
+	if err != nil {
+		return nil, err
+	}
+	typesJSON, err := json.Marshal(append([]string{}, relationTypes...))
+	if err != nil {
+		return nil, err
+	}
+
+	var ends string
+	switch direction {
+	case DirectionOut:
+		ends = "ef.name IN (SELECT value FROM json_each(?1))"
+	case DirectionIn:
+		ends = "et.name IN (SELECT value FROM json_each(?1))"
+	default:
+		ends = "(ef.name IN (SELECT value FROM json_each(?1)) OR et.name IN (SELECT value FROM json_each(?1)))"
+	}
+
+	return s.queryRelations(ctx, sqliteRelationColumns+`
+        WHERE `+ends+`
+          AND (json_array_length(?2) = 0 OR r.relation_type IN (SELECT value FROM json_each(?2)))
+        ORDER BY ef.name, et.name, r.relation_type
+    `, string(namesJSON), string(typesJSON))
+}
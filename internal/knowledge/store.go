@@ -0,0 +1,71 @@
+package knowledge
+
+import (
+	"context"
+	"mcp-compose-memory/internal/models"
+)
+
+// Store is the persistence backend behind Manager. Implementations must
+// provide the same semantics as PostgresStore: duplicate entities and
+// observations are ignored, relations with a missing endpoint are skipped,
+// and deleting an entity removes its observations and relations. A write
+// whose context is cancelled before it completes must leave the graph
+// unchanged.
+type Store interface {
+	CreateEntities(ctx context.Context, entities []models.Entity) ([]models.Entity, error)
+	CreateRelations(ctx context.Context, relations []models.Relation) ([]models.Relation, error)
+	AddObservations(ctx context.Context, observations []models.ObservationAddition) ([]models.ObservationResult, error)
+	DeleteEntities(ctx context.Context, entityNames []string) error
+	DeleteObservations(ctx context.Context, deletions []models.ObservationDeletion) error
+	DeleteRelations(ctx context.Context, relations []models.Relation) error
+	ReadGraph(ctx context.Context) (*models.KnowledgeGraph, error)
+	SearchNodes(ctx context.Context, query string) (*models.KnowledgeGraph, error)
+	ReadGraphPage(ctx context.Context, page Page) (*models.KnowledgeGraph, bool, error)
+	SearchNodesPage(ctx context.Context, query string, page Page) (*models.KnowledgeGraph, bool, error)
+	OpenNodes(ctx context.Context, names []string) (*models.KnowledgeGraph, error)
+	Complete(ctx context.Context, field CompletionField, prefix string, limit int) ([]string, error)
+	Neighborhood(ctx context.Context, query NeighborhoodQuery) (*models.Neighborhood, error)
+	// Adjacent returns the relations of the given types, or of every type
+	// if relationTypes is empty, that lead away from the named entities in
+	// direction
+	Adjacent(ctx context.Context, names []string, direction Direction, relationTypes []string) ([]models.Relation, error)
+	Close() error
+}
+
+// Page selects a window of entities in name order for ReadGraphPage and
+// SearchNodesPage. Each page holds the relations that start at one of its
+// entities, so walking every page visits each relation exactly once. The
+// page methods also report whether more entities follow the page.
+type Page struct {
+	// After excludes entities whose name sorts at or before it
+	After string
+	// Limit is the maximum number of entities in the page and must be positive
+	Limit int
+}
+
+// trimPage cuts the extra entity fetched to detect a following page and
+// returns the names of the entities that remain
+func trimPage(entities []models.Entity, limit int) ([]models.Entity, []string, bool) {
+	more := len(entities) > limit
+	if more {
+		entities = entities[:limit]
+	}
+
+	names := make([]string, len(entities))
+	for i, entity := range entities {
+		names[i] = entity.Name
+	}
+	return entities, names, more
+}
+
+// CompletionField selects the values Store.Complete suggests
+type CompletionField int
+
+const (
+	// CompleteEntityName suggests entity names, most recently changed first
+	CompleteEntityName CompletionField = iota
+	// CompleteEntityType suggests entity types, most used first
+	CompleteEntityType
+	// CompleteRelationType suggests relation types, most used first
+	CompleteRelationType
+)
@@ -0,0 +1,476 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mcp-compose-memory/internal/database"
+	"mcp-compose-memory/internal/models"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// storeFactories open an empty instance of each Store that runs without an
+// external server
+var storeFactories = []struct {
+	name string
+	open func(t *testing.T) Store
+}{
+	{"memory", func(t *testing.T) Store {
+		return NewMemoryStore()
+	}},
+	{"file", func(t *testing.T) Store {
+		store, err := NewFileStore(filepath.Join(t.TempDir(), "memory.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}},
+	{"sqlite", func(t *testing.T) Store {
+		db, err := database.NewSQLiteConnection(filepath.Join(t.TempDir(), "memory.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := database.RunSQLiteMigrations(db); err != nil {
+			db.Close()
+			t.Fatal(err)
+		}
+		return NewSQLiteStore(db)
+	}},
+}
+
+// forEachStore runs test against a fresh instance of every Store
+func forEachStore(t *testing.T, test func(t *testing.T, store Store)) {
+	for _, factory := range storeFactories {
+		t.Run(factory.name, func(t *testing.T) {
+			store := factory.open(t)
+			defer store.Close()
+			test(t, store)
+		})
+	}
+}
+
+// readGraph returns the graph held by store with its entities, observations
+// and relations in a fixed order, so that stores can be compared
+func readGraph(t *testing.T, store Store) *models.KnowledgeGraph {
+	t.Helper()
+
+	graph, err := store.ReadGraph(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(graph.Entities, func(i, j int) bool { return graph.Entities[i].Name < graph.Entities[j].Name })
+	for i := range graph.Entities {
+		sort.Strings(graph.Entities[i].Observations)
+	}
+	sortRelations(graph.Relations)
+	return graph
+}
+
+// seedStore creates the entities at either end of relations and the loners,
+// all of type node, then the relations
+func seedStore(t *testing.T, store Store, relations []models.Relation, loners ...string) {
+	t.Helper()
+	ctx := context.Background()
+
+	seen := make(map[string]bool)
+	var entities []models.Entity
+	for _, relation := range relations {
+		for _, name := range []string{relation.From, relation.To} {
+			if !seen[name] {
+				seen[name] = true
+				entities = append(entities, models.Entity{Name: name, EntityType: "node", Observations: []string{}})
+			}
+		}
+	}
+	for _, name := range loners {
+		entities = append(entities, models.Entity{Name: name, EntityType: "node", Observations: []string{}})
+	}
+
+	if _, err := store.CreateEntities(ctx, entities); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateRelations(ctx, relations); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestManager returns a Manager over an in-memory graph seeded by
+// seedStore
+func newTestManager(t *testing.T, relations []models.Relation, loners ...string) *Manager {
+	t.Helper()
+
+	store := NewMemoryStore()
+	seedStore(t, store, relations, loners...)
+	return NewManager(store)
+}
+
+func entityNames(entities []models.Entity) []string {
+	names := []string{}
+	for _, entity := range entities {
+		names = append(names, entity.Name)
+	}
+	return names
+}
+
+func TestStoreCreateEntitiesIgnoresDuplicates(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+
+		tests := []struct {
+			name     string
+			entities []models.Entity
+			want     []string
+		}{
+			{"new", []models.Entity{
+				{Name: "alice", EntityType: "person", Observations: []string{"likes tea"}},
+				{Name: "bob", EntityType: "person"},
+			}, []string{"alice", "bob"}},
+			{"existing", []models.Entity{
+				{Name: "alice", EntityType: "robot", Observations: []string{"likes oil"}},
+			}, []string{}},
+			{"mixed", []models.Entity{
+				{Name: "bob", EntityType: "person"},
+				{Name: "carol", EntityType: "person"},
+			}, []string{"carol"}},
+		}
+
+		for _, tt := range tests {
+			created, err := store.CreateEntities(ctx, tt.entities)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if got := entityNames(created); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: created %v, want %v", tt.name, got, tt.want)
+			}
+		}
+
+		// The duplicate did not overwrite the original
+		graph := readGraph(t, store)
+		alice := graph.Entities[0]
+		if alice.EntityType != "person" || !reflect.DeepEqual(alice.Observations, []string{"likes tea"}) {
+			t.Errorf("alice = %+v, want the original entity", alice)
+		}
+	})
+}
+
+func TestStoreAddObservationsIgnoresDuplicates(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		if _, err := store.CreateEntities(ctx, []models.Entity{
+			{Name: "alice", EntityType: "person", Observations: []string{"likes tea"}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		results, err := store.AddObservations(ctx, []models.ObservationAddition{
+			{EntityName: "alice", Contents: []string{"likes tea", "plays chess"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || !reflect.DeepEqual(results[0].AddedObservations, []string{"plays chess"}) {
+			t.Errorf("results = %+v, want only plays chess added", results)
+		}
+
+		graph := readGraph(t, store)
+		if want := []string{"likes tea", "plays chess"}; !reflect.DeepEqual(graph.Entities[0].Observations, want) {
+			t.Errorf("observations = %v, want %v", graph.Entities[0].Observations, want)
+		}
+	})
+}
+
+func TestStoreAddObservationsMissingEntity(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		if _, err := store.CreateEntities(ctx, []models.Entity{{Name: "alice", EntityType: "person"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := store.AddObservations(ctx, []models.ObservationAddition{
+			{EntityName: "alice", Contents: []string{"likes tea"}},
+			{EntityName: "nobody", Contents: []string{"is missing"}},
+		})
+		if !errors.Is(err, ErrNotFound) {
+			t.Fatalf("err = %v, want ErrNotFound", err)
+		}
+
+		// The failed call left the graph unchanged
+		graph := readGraph(t, store)
+		if len(graph.Entities[0].Observations) != 0 {
+			t.Errorf("observations = %v, want none", graph.Entities[0].Observations)
+		}
+	})
+}
+
+func TestStoreCreateRelations(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		if _, err := store.CreateEntities(ctx, []models.Entity{
+			{Name: "alice", EntityType: "person"},
+			{Name: "bob", EntityType: "person"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		knows := models.Relation{From: "alice", To: "bob", RelationType: "knows"}
+		tests := []struct {
+			name      string
+			relations []models.Relation
+			want      int
+		}{
+			{"new", []models.Relation{knows}, 1},
+			{"duplicate", []models.Relation{knows}, 0},
+			{"missing target", []models.Relation{{From: "alice", To: "nobody", RelationType: "knows"}}, 0},
+			{"missing source", []models.Relation{{From: "nobody", To: "bob", RelationType: "knows"}}, 0},
+			{"other type", []models.Relation{{From: "alice", To: "bob", RelationType: "trusts"}}, 1},
+		}
+
+		for _, tt := range tests {
+			created, err := store.CreateRelations(ctx, tt.relations)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if len(created) != tt.want {
+				t.Errorf("%s: created %v, want %d relations", tt.name, created, tt.want)
+			}
+		}
+
+		want := []models.Relation{knows, {From: "alice", To: "bob", RelationType: "trusts"}}
+		if graph := readGraph(t, store); !reflect.DeepEqual(graph.Relations, want) {
+			t.Errorf("relations = %v, want %v", graph.Relations, want)
+		}
+	})
+}
+
+func TestStoreDeleteEntitiesCascades(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		if _, err := store.CreateEntities(ctx, []models.Entity{
+			{Name: "alice", EntityType: "person", Observations: []string{"likes tea"}},
+			{Name: "bob", EntityType: "person"},
+			{Name: "carol", EntityType: "person"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.CreateRelations(ctx, []models.Relation{
+			{From: "alice", To: "bob", RelationType: "knows"},
+			{From: "bob", To: "carol", RelationType: "knows"},
+			{From: "carol", To: "alice", RelationType: "knows"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := store.DeleteEntities(ctx, []string{"alice", "nobody"}); err != nil {
+			t.Fatal(err)
+		}
+
+		graph := readGraph(t, store)
+		if got, want := entityNames(graph.Entities), []string{"bob", "carol"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("entities = %v, want %v", got, want)
+		}
+		want := []models.Relation{{From: "bob", To: "carol", RelationType: "knows"}}
+		if !reflect.DeepEqual(graph.Relations, want) {
+			t.Errorf("relations = %v, want %v", graph.Relations, want)
+		}
+
+		// Recreating the entity does not bring back its observations
+		if _, err := store.CreateEntities(ctx, []models.Entity{{Name: "alice", EntityType: "person"}}); err != nil {
+			t.Fatal(err)
+		}
+		graph = readGraph(t, store)
+		if len(graph.Entities[0].Observations) != 0 {
+			t.Errorf("observations = %v, want none", graph.Entities[0].Observations)
+		}
+	})
+}
+
+func TestStoreCancelledWrite(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if _, err := store.CreateEntities(ctx, []models.Entity{{Name: "alice", EntityType: "person"}}); err == nil {
+			t.Fatal("CreateEntities succeeded with a cancelled context")
+		}
+		if graph := readGraph(t, store); len(graph.Entities) != 0 {
+			t.Errorf("entities = %v, want none", graph.Entities)
+		}
+	})
+}
+
+// walkPages reads every page of limit entities through read and returns
+// the entities and relations in the order read, failing on an empty page
+// before the last
+func walkPages(t *testing.T, limit int, read func(page Page) (*models.KnowledgeGraph, bool, error)) ([]string, []models.Relation) {
+	t.Helper()
+
+	var names []string
+	var relations []models.Relation
+	page := Page{Limit: limit}
+	for {
+		graph, more, err := read(page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(graph.Entities) > limit {
+			t.Fatalf("page after %q holds %d entities, limit %d", page.After, len(graph.Entities), limit)
+		}
+		names = append(names, entityNames(graph.Entities)...)
+		relations = append(relations, graph.Relations...)
+		if !more {
+			return names, relations
+		}
+		if len(graph.Entities) == 0 {
+			t.Fatalf("empty page after %q reports more", page.After)
+		}
+		page.After = graph.Entities[len(graph.Entities)-1].Name
+	}
+}
+
+func TestStorePages(t *testing.T) {
+	var entities []models.Entity
+	var relations []models.Relation
+	for i := 0; i < 7; i++ {
+		entityType := "person"
+		if i%2 == 1 {
+			entityType = "place"
+		}
+		entities = append(entities, models.Entity{Name: fmt.Sprintf("n%d", i), EntityType: entityType, Observations: []string{}})
+		relations = append(relations, models.Relation{From: fmt.Sprintf("n%d", i), To: fmt.Sprintf("n%d", (i+1)%7), RelationType: "next"})
+	}
+
+	forEachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		if _, err := store.CreateEntities(ctx, entities); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.CreateRelations(ctx, relations); err != nil {
+			t.Fatal(err)
+		}
+		whole := readGraph(t, store)
+
+		for _, limit := range []int{1, 2, 3, 7, 10} {
+			// Every entity and relation appears on exactly one page
+			names, got := walkPages(t, limit, func(page Page) (*models.KnowledgeGraph, bool, error) {
+				return store.ReadGraphPage(ctx, page)
+			})
+			if want := entityNames(whole.Entities); !reflect.DeepEqual(names, want) {
+				t.Errorf("limit %d: read %v, want %v", limit, names, want)
+			}
+			sortRelations(got)
+			if !reflect.DeepEqual(got, whole.Relations) {
+				t.Errorf("limit %d: read relations %v, want %v", limit, got, whole.Relations)
+			}
+
+			names, _ = walkPages(t, limit, func(page Page) (*models.KnowledgeGraph, bool, error) {
+				return store.SearchNodesPage(ctx, "person", page)
+			})
+			if want := []string{"n0", "n2", "n4", "n6"}; !reflect.DeepEqual(names, want) {
+				t.Errorf("limit %d: found %v, want %v", limit, names, want)
+			}
+		}
+
+		// A page resumes after the name in its cursor even once that entity
+		// is gone
+		if err := store.DeleteEntities(ctx, []string{"n2"}); err != nil {
+			t.Fatal(err)
+		}
+		graph, more, err := store.ReadGraphPage(ctx, Page{After: "n2", Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := entityNames(graph.Entities); !reflect.DeepEqual(got, []string{"n3", "n4"}) || !more {
+			t.Errorf("page after deleted n2 = %v, more %v; want [n3 n4], more", got, more)
+		}
+	})
+}
+
+func TestStoreCancelledRead(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		if _, err := store.CreateEntities(context.Background(), []models.Entity{{Name: "alice", EntityType: "person"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if _, err := store.ReadGraph(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("ReadGraph: err = %v, want context.Canceled", err)
+		}
+		if _, err := store.SearchNodes(ctx, "alice"); !errors.Is(err, context.Canceled) {
+			t.Errorf("SearchNodes: err = %v, want context.Canceled", err)
+		}
+		if _, _, err := store.ReadGraphPage(ctx, Page{Limit: 10}); !errors.Is(err, context.Canceled) {
+			t.Errorf("ReadGraphPage: err = %v, want context.Canceled", err)
+		}
+		if _, err := store.Neighborhood(ctx, NeighborhoodQuery{Names: []string{"alice"}, Depth: 1, Direction: DirectionBoth}); !errors.Is(err, context.Canceled) {
+			t.Errorf("Neighborhood: err = %v, want context.Canceled", err)
+		}
+	})
+}
+
+func TestStoreSearchNodesStems(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"running", []string{"jogger"}},
+		{"run", []string{"jogger"}},
+		{"studies", []string{"scholar"}},
+		{"noted", []string{"scholar"}},
+		{"morning runs", []string{"jogger"}},
+		{"walking", nil},
+	}
+
+	forEachStore(t, func(t *testing.T, store Store) {
+		if _, err := store.CreateEntities(context.Background(), []models.Entity{
+			{Name: "jogger", EntityType: "person", Observations: []string{"Runs every morning"}},
+			{Name: "scholar", EntityType: "person", Observations: []string{"Keeps a study note"}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.query, func(t *testing.T) {
+				graph, err := store.SearchNodes(context.Background(), tt.query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := entityNames(graph.Entities); len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+					t.Errorf("SearchNodes(%q) = %v, want %v", tt.query, got, tt.want)
+				}
+			})
+		}
+	})
+}
+
+func TestFileStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.json")
+	ctx := context.Background()
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateEntities(ctx, []models.Entity{
+		{Name: "alice", EntityType: "person", Observations: []string{"likes tea"}},
+		{Name: "bob", EntityType: "person"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateRelations(ctx, []models.Relation{{From: "alice", To: "bob", RelationType: "knows"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := readGraph(t, store)
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readGraph(t, reopened); !reflect.DeepEqual(got, want) {
+		t.Errorf("reopened graph = %+v, want %+v", got, want)
+	}
+}
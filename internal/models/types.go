@@ -1,24 +1,27 @@
 package models
 
-import "time"
+import (
+    "encoding/json"
+    "time"
+)
 
 // Entity represents an entity in the knowledge graph
 type Entity struct {
-    ID           int       `json:"id" db:"id"`
-    Name         string    `json:"name" db:"name"`
-    EntityType   string    `json:"entityType" db:"entity_type"`
-    Observations []string  `json:"observations"`
-    CreatedAt    time.Time `json:"createdAt" db:"created_at"`
-    UpdatedAt    time.Time `json:"updatedAt" db:"updated_at"`
+    ID           int       `json:"id" db:"id" jsonschema:"-"`
+    Name         string    `json:"name" db:"name" jsonschema:"minLength=1,maxLength=256" description:"The name of the entity"`
+    EntityType   string    `json:"entityType" db:"entity_type" jsonschema:"minLength=1,maxLength=256" description:"The type of the entity"`
+    Observations []string  `json:"observations" jsonschema:"maxItems=1000,items.minLength=1,items.maxLength=4096" description:"An array of observation contents associated with the entity"`
+    CreatedAt    time.Time `json:"createdAt" db:"created_at" jsonschema:"-"`
+    UpdatedAt    time.Time `json:"updatedAt" db:"updated_at" jsonschema:"-"`
 }
 
 // Relation represents a relationship between entities
 type Relation struct {
-    ID           int       `json:"id" db:"id"`
-    From         string    `json:"from"`
-    To           string    `json:"to"`
-    RelationType string    `json:"relationType" db:"relation_type"`
-    CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+    ID           int       `json:"id" db:"id" jsonschema:"-"`
+    From         string    `json:"from" jsonschema:"minLength=1,maxLength=256" description:"The name of the entity where the relation starts"`
+    To           string    `json:"to" jsonschema:"minLength=1,maxLength=256" description:"The name of the entity where the relation ends"`
+    RelationType string    `json:"relationType" db:"relation_type" jsonschema:"minLength=1,maxLength=256" description:"The type of the relation"`
+    CreatedAt    time.Time `json:"createdAt" db:"created_at" jsonschema:"-"`
 }
 
 // Observation represents an observation about an entity
@@ -35,6 +38,130 @@ type KnowledgeGraph struct {
     Relations []Relation `json:"relations"`
 }
 
+// GraphPage is one page of a paginated read_graph or search_nodes result.
+// NextCursor is empty on the last page.
+type GraphPage struct {
+    KnowledgeGraph
+    NextCursor string `json:"nextCursor,omitempty" description:"Cursor for the next page, absent on the last page"`
+}
+
+// Neighborhood is the subgraph around a set of entities
+type Neighborhood struct {
+    KnowledgeGraph
+    Depths    map[string]int `json:"depths" description:"Number of hops from the nearest requested entity, by entity name"`
+    Truncated bool           `json:"truncated" description:"Whether the neighborhood reached its size limit, so that the entities farthest away may be missing"`
+}
+
+// Path is a chain of relations from one entity to another
+type Path struct {
+    Entities  []string   `json:"entities" description:"Names of the entities along the path, from the first entity to the last"`
+    Relations []Relation `json:"relations" description:"The relation between each pair of consecutive entities, as stored"`
+}
+
+// EntityStats describes the place of an entity in the graph
+type EntityStats struct {
+    Name       string  `json:"name"`
+    EntityType string  `json:"entityType"`
+    InDegree   int     `json:"inDegree" description:"Number of relations to the entity"`
+    OutDegree  int     `json:"outDegree" description:"Number of relations from the entity"`
+    PageRank   float64 `json:"pageRank" description:"PageRank of the entity; the ranks of all entities sum to 1"`
+    Component  int     `json:"component" description:"ID of the connected component holding the entity"`
+}
+
+// Component is a weakly connected component: a group of entities joined by
+// relations followed in either direction
+type Component struct {
+    ID       int      `json:"id"`
+    Size     int      `json:"size"`
+    Entities []string `json:"entities" description:"Names of the entities in the component"`
+}
+
+// GraphStats summarizes the structure of the knowledge graph
+type GraphStats struct {
+    EntityCount    int           `json:"entityCount"`
+    RelationCount  int           `json:"relationCount"`
+    ComponentCount int           `json:"componentCount"`
+    OrphanCount    int           `json:"orphanCount"`
+    Entities       []EntityStats `json:"entities" description:"Entities by descending PageRank"`
+    Components     []Component   `json:"components" description:"Connected components by descending size"`
+    Orphans        []string      `json:"orphans" description:"Names of the entities without relations"`
+}
+
+// Top returns the stats with at most limit entities, components, orphans,
+// and entities per component listed. The counts still cover the whole
+// graph. A limit of zero or less lists everything.
+func (s GraphStats) Top(limit int) GraphStats {
+    if limit <= 0 {
+        return s
+    }
+
+    if len(s.Entities) > limit {
+        s.Entities = s.Entities[:limit]
+    }
+    if len(s.Orphans) > limit {
+        s.Orphans = s.Orphans[:limit]
+    }
+
+    components := s.Components
+    if len(components) > limit {
+        components = components[:limit]
+    }
+    s.Components = make([]Component, len(components))
+    for i, component := range components {
+        if len(component.Entities) > limit {
+            component.Entities = component.Entities[:limit]
+        }
+        s.Components[i] = component
+    }
+
+    return s
+}
+
+// TypeCount is how often an entity or relation type occurs
+type TypeCount struct {
+    Type  string `json:"type"`
+    Count int    `json:"count"`
+}
+
+// Community is a group of entities more densely related to each other than
+// to the rest of the graph
+type Community struct {
+    ID            int         `json:"id"`
+    Size          int         `json:"size"`
+    Members       []string    `json:"members" description:"Names of the entities in the community"`
+    EntityTypes   []TypeCount `json:"entityTypes" description:"The most frequent entity types among the members"`
+    RelationTypes []TypeCount `json:"relationTypes" description:"The most frequent types of the relations among the members"`
+}
+
+// Communities partitions the entities of the graph into communities
+type Communities struct {
+    CommunityCount int         `json:"communityCount"`
+    Modularity     float64     `json:"modularity" description:"Modularity of the partition, from -0.5 to 1; higher means more distinct communities"`
+    Communities    []Community `json:"communities" description:"Communities by descending size"`
+}
+
+// Top returns the communities with at least minSize members, at most limit
+// of them with at most limit members listed each. CommunityCount still
+// counts every community. A limit of zero or less lists everything.
+func (c Communities) Top(limit, minSize int) Communities {
+    communities := []Community{}
+    for _, community := range c.Communities {
+        if community.Size < minSize {
+            continue
+        }
+        if limit > 0 && len(communities) == limit {
+            break
+        }
+        if limit > 0 && len(community.Members) > limit {
+            community.Members = community.Members[:limit]
+        }
+        communities = append(communities, community)
+    }
+
+    c.Communities = communities
+    return c
+}
+
 // MCP Protocol types
 type MCPRequest struct {
     ID      interface{} `json:"id"`
@@ -50,6 +177,13 @@ type MCPResponse struct {
     Error   *MCPError   `json:"error,omitempty"`
 }
 
+// MCPNotification is a JSON-RPC message that expects no response
+type MCPNotification struct {
+    JSONRPC string      `json:"jsonrpc"`
+    Method  string      `json:"method"`
+    Params  interface{} `json:"params,omitempty"`
+}
+
 type MCPError struct {
     Code    int         `json:"code"`
     Message string      `json:"message"`
@@ -68,45 +202,247 @@ type ToolContent struct {
     Text string `json:"text"`
 }
 
+// Tool is a tool definition as listed by tools/list
+type Tool struct {
+    Name         string                 `json:"name"`
+    Description  string                 `json:"description"`
+    InputSchema  map[string]interface{} `json:"inputSchema"`
+    OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
+    Annotations  *ToolAnnotations       `json:"annotations,omitempty"`
+}
+
+// ToolAnnotations are hints about a tool's behavior. Unset hints take the
+// defaults given by the MCP specification, which are not all false, so they
+// are pointers.
+type ToolAnnotations struct {
+    Title           string `json:"title,omitempty"`
+    ReadOnlyHint    *bool  `json:"readOnlyHint,omitempty"`
+    DestructiveHint *bool  `json:"destructiveHint,omitempty"`
+    IdempotentHint  *bool  `json:"idempotentHint,omitempty"`
+    OpenWorldHint   *bool  `json:"openWorldHint,omitempty"`
+}
+
+// ToolResponse is the result of tools/call. StructuredContent holds the same
+// result as the text content, as a JSON object matching the tool's
+// outputSchema.
 type ToolResponse struct {
-    Content []ToolContent `json:"content"`
+    Content           []ToolContent `json:"content"`
+    StructuredContent interface{}   `json:"structuredContent,omitempty"`
+    IsError           bool          `json:"isError,omitempty"`
+}
+
+// PaginatedParams are the parameters of list requests that support
+// cursor-based pagination
+type PaginatedParams struct {
+    Cursor string `json:"cursor,omitempty"`
+}
+
+// ResourceParams are the parameters of requests that name a single resource
+type ResourceParams struct {
+    URI string `json:"uri"`
+}
+
+// Resource is a piece of context the server exposes by URI
+type Resource struct {
+    URI         string `json:"uri"`
+    Name        string `json:"name"`
+    Description string `json:"description,omitempty"`
+    MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceTemplate describes a family of resources by RFC 6570 URI template
+type ResourceTemplate struct {
+    URITemplate string `json:"uriTemplate"`
+    Name        string `json:"name"`
+    Description string `json:"description,omitempty"`
+    MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceContents is the body of a resource returned by resources/read
+type ResourceContents struct {
+    URI      string `json:"uri"`
+    MimeType string `json:"mimeType,omitempty"`
+    Text     string `json:"text"`
+}
+
+// PromptArgument describes a parameter of a prompt template
+type PromptArgument struct {
+    Name        string `json:"name"`
+    Description string `json:"description,omitempty"`
+    Required    bool   `json:"required,omitempty"`
+}
+
+// PromptInfo is how a prompt is advertised by prompts/list
+type PromptInfo struct {
+    Name        string           `json:"name"`
+    Description string           `json:"description,omitempty"`
+    Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// PromptMessage is one message of a rendered prompt
+type PromptMessage struct {
+    Role    string      `json:"role"`
+    Content ToolContent `json:"content"`
+}
+
+// PromptGetParams are the parameters of prompts/get
+type PromptGetParams struct {
+    Name      string            `json:"name"`
+    Arguments map[string]string `json:"arguments"`
+}
+
+// CompletionParams are the parameters of completion/complete
+type CompletionParams struct {
+    Ref struct {
+        Type string `json:"type"`
+        Name string `json:"name,omitempty"`
+        URI  string `json:"uri,omitempty"`
+    } `json:"ref"`
+    Argument struct {
+        Name  string `json:"name"`
+        Value string `json:"value"`
+    } `json:"argument"`
+}
+
+// Completion is the result of completion/complete
+type Completion struct {
+    Values  []string `json:"values"`
+    Total   int      `json:"total,omitempty"`
+    HasMore bool     `json:"hasMore"`
 }
 
 // Input schemas for tools
 type CreateEntitiesInput struct {
-    Entities []Entity `json:"entities"`
+    Entities []Entity `json:"entities" jsonschema:"maxItems=1000"`
 }
 
 type CreateRelationsInput struct {
-    Relations []Relation `json:"relations"`
+    Relations []Relation `json:"relations" jsonschema:"maxItems=1000"`
+}
+
+// ObservationAddition is a batch of observations to add to one entity
+type ObservationAddition struct {
+    EntityName string   `json:"entityName" jsonschema:"minLength=1,maxLength=256" description:"The name of the entity to add the observations to"`
+    Contents   []string `json:"contents" jsonschema:"maxItems=1000,items.minLength=1,items.maxLength=4096" description:"An array of observation contents to add"`
+}
+
+// ObservationResult reports which observations were actually added to an entity
+type ObservationResult struct {
+    EntityName        string   `json:"entityName"`
+    AddedObservations []string `json:"addedObservations"`
+}
+
+// ObservationDeletion is a batch of observations to remove from one entity
+type ObservationDeletion struct {
+    EntityName   string   `json:"entityName" jsonschema:"minLength=1,maxLength=256" description:"The name of the entity containing the observations"`
+    Observations []string `json:"observations" jsonschema:"maxItems=1000,items.maxLength=4096" description:"An array of observations to delete"`
 }
 
 type AddObservationsInput struct {
-    Observations []struct {
-        EntityName string   `json:"entityName"`
-        Contents   []string `json:"contents"`
-    } `json:"observations"`
+    Observations []ObservationAddition `json:"observations" jsonschema:"maxItems=1000"`
+}
+
+// Structured results of the write tools
+type CreateEntitiesResult struct {
+    Entities []Entity `json:"entities" description:"The entities that were created; entities that already existed are left out"`
+}
+
+type CreateRelationsResult struct {
+    Relations []Relation `json:"relations" description:"The relations that were created; existing relations and relations with a missing endpoint are left out"`
+}
+
+type AddObservationsResult struct {
+    Results []ObservationResult `json:"results"`
+}
+
+type DeleteResult struct {
+    Message string `json:"message"`
+}
+
+// FindPathsResult is truncated if the search reached its limits, in which
+// case paths may be missing
+type FindPathsResult struct {
+    ShortestPaths []Path `json:"shortestPaths" description:"The paths with the fewest relations"`
+    Paths         []Path `json:"paths,omitempty" description:"Every path that visits no entity twice, when allPaths is set"`
+    Truncated     bool   `json:"truncated" description:"Whether the search stopped early, so that paths may be missing"`
+}
+
+// Text returns the result as the bare array earlier versions returned
+func (r CreateEntitiesResult) Text() string {
+    data, _ := json.Marshal(r.Entities)
+    return string(data)
+}
+
+// Text returns the result as the bare array earlier versions returned
+func (r CreateRelationsResult) Text() string {
+    data, _ := json.Marshal(r.Relations)
+    return string(data)
+}
+
+// Text returns the result as the bare array earlier versions returned
+func (r AddObservationsResult) Text() string {
+    data, _ := json.Marshal(r.Results)
+    return string(data)
+}
+
+func (r DeleteResult) Text() string {
+    return r.Message
 }
 
 type DeleteEntitiesInput struct {
-    EntityNames []string `json:"entityNames"`
+    EntityNames []string `json:"entityNames" jsonschema:"maxItems=1000,items.maxLength=256" description:"An array of entity names to delete"`
 }
 
 type DeleteObservationsInput struct {
-    Deletions []struct {
-        EntityName   string   `json:"entityName"`
-        Observations []string `json:"observations"`
-    } `json:"deletions"`
+    Deletions []ObservationDeletion `json:"deletions" jsonschema:"maxItems=1000"`
 }
 
 type DeleteRelationsInput struct {
-    Relations []Relation `json:"relations"`
+    Relations []Relation `json:"relations" jsonschema:"maxItems=1000" description:"An array of relations to delete"`
+}
+
+// ReadGraphInput pages through the graph when Limit or Cursor is set
+type ReadGraphInput struct {
+    Limit  int    `json:"limit,omitempty" jsonschema:"minimum=1,maximum=1000" description:"Maximum number of entities in the page"`
+    Cursor string `json:"cursor,omitempty" jsonschema:"maxLength=1024" description:"The nextCursor of the previous page"`
 }
 
 type SearchNodesInput struct {
-    Query string `json:"query"`
+    Query  string `json:"query" jsonschema:"maxLength=1024" description:"The search query to match against entity names, types, and observation content"`
+    Limit  int    `json:"limit,omitempty" jsonschema:"minimum=1,maximum=1000" description:"Maximum number of entities in the page"`
+    Cursor string `json:"cursor,omitempty" jsonschema:"maxLength=1024" description:"The nextCursor of the previous page"`
 }
 
 type OpenNodesInput struct {
-    Names []string `json:"names"`
+    Names []string `json:"names" jsonschema:"maxItems=1000,items.maxLength=256" description:"An array of entity names to retrieve"`
+}
+
+// FindPathsInput asks for the paths between two entities; Direction
+// defaults to both, MaxLength to 6, and MaxPaths to 100
+type FindPathsInput struct {
+    From          string   `json:"from" jsonschema:"minLength=1,maxLength=256" description:"Name of the entity the paths start at"`
+    To            string   `json:"to" jsonschema:"minLength=1,maxLength=256" description:"Name of the entity the paths end at"`
+    Direction     string   `json:"direction,omitempty" jsonschema:"enum=in|out|both" description:"Follow relations from source to target (out), target to source (in), or either way (both, the default)"`
+    RelationTypes []string `json:"relationTypes,omitempty" jsonschema:"maxItems=100,items.maxLength=256" description:"Only follow relations of these types"`
+    MaxLength     int      `json:"maxLength,omitempty" jsonschema:"minimum=1,maximum=10" description:"Maximum number of relations in a path (default 6)"`
+    AllPaths      bool     `json:"allPaths,omitempty" description:"Also return every path up to maxLength that visits no entity twice"`
+    MaxPaths      int      `json:"maxPaths,omitempty" jsonschema:"minimum=1,maximum=1000" description:"Maximum number of paths of each kind to return (default 100)"`
+}
+
+type DetectCommunitiesInput struct {
+    Limit   int `json:"limit,omitempty" jsonschema:"minimum=1,maximum=1000" description:"Maximum number of communities, and of members per community, to list (default 20)"`
+    MinSize int `json:"minSize,omitempty" jsonschema:"minimum=1" description:"Only list communities with at least this many members (default 2)"`
+}
+
+type GraphStatsInput struct {
+    Limit int `json:"limit,omitempty" jsonschema:"minimum=1,maximum=1000" description:"Maximum number of entities, components, and orphans to list (default 20)"`
+}
+
+// GetNeighborhoodInput expands the graph around Names; Depth defaults to 1
+// and Direction to both
+type GetNeighborhoodInput struct {
+    Names         []string `json:"names" jsonschema:"minItems=1,maxItems=1000,items.maxLength=256" description:"Names of the entities to start from"`
+    Depth         *int     `json:"depth,omitempty" jsonschema:"minimum=0,maximum=10" description:"Maximum number of relations to follow from the starting entities (default 1)"`
+    Direction     string   `json:"direction,omitempty" jsonschema:"enum=in|out|both" description:"Follow relations from source to target (out), target to source (in), or either way (both, the default)"`
+    RelationTypes []string `json:"relationTypes,omitempty" jsonschema:"maxItems=100,items.maxLength=256" description:"Only follow and return relations of these types"`
 }
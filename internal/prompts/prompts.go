@@ -0,0 +1,156 @@
+package prompts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mcp-compose-memory/internal/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Prompt is a parameterized prompt template. Template is text/template
+// source rendered with the prompt arguments as data, so {{.name}} expands
+// to the "name" argument. The functions passed to Render let a template
+// pre-fill the prompt with knowledge graph content.
+type Prompt struct {
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Arguments   []models.PromptArgument `json:"arguments"`
+	Template    string                  `json:"template"`
+}
+
+// Info returns the prompt as advertised by prompts/list
+func (p *Prompt) Info() models.PromptInfo {
+	return models.PromptInfo{
+		Name:        p.Name,
+		Description: p.Description,
+		Arguments:   p.Arguments,
+	}
+}
+
+// ErrMissingArgument is returned by Render when a required argument is
+// missing or blank
+var ErrMissingArgument = errors.New("missing required argument")
+
+// FuncNames lists the template functions Render callers must provide
+var FuncNames = []string{"openNodes", "searchNodes", "entitiesOfType"}
+
+// placeholderFuncs lets templates be parsed before the real functions exist
+func placeholderFuncs() template.FuncMap {
+	funcs := template.FuncMap{}
+	for _, name := range FuncNames {
+		funcs[name] = func(...interface{}) (string, error) { return "", nil }
+	}
+	return funcs
+}
+
+func (p *Prompt) parse(funcs template.FuncMap) (*template.Template, error) {
+	return template.New(p.Name).Option("missingkey=zero").Funcs(funcs).Parse(p.Template)
+}
+
+// Render checks the required arguments and expands the template. Errors of
+// the template functions are wrapped in the error returned.
+func (p *Prompt) Render(args map[string]string, funcs template.FuncMap) (string, error) {
+	for _, arg := range p.Arguments {
+		if arg.Required && strings.TrimSpace(args[arg.Name]) == "" {
+			return "", fmt.Errorf("%w %q", ErrMissingArgument, arg.Name)
+		}
+	}
+
+	tmpl, err := p.parse(funcs)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, args); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// LoadDir reads every *.json prompt definition in dir, in name order. Each
+// file holds one Prompt object.
+func LoadDir(dir string) ([]*Prompt, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var loaded []*Prompt
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var prompt Prompt
+		if err := json.Unmarshal(data, &prompt); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if prompt.Name == "" {
+			return nil, fmt.Errorf("%s: prompt name is required", path)
+		}
+		if _, err := prompt.parse(placeholderFuncs()); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		log.Printf("Loaded prompt %s from %s", prompt.Name, path)
+		loaded = append(loaded, &prompt)
+	}
+
+	return loaded, nil
+}
+
+// Builtin returns the prompts every server offers
+func Builtin() []*Prompt {
+	return []*Prompt{
+		{
+			Name:        "use_memory",
+			Description: "Instructions for keeping a persistent memory with the knowledge graph tools",
+			Template: `You have access to a persistent knowledge graph memory through these tools:
+
+- search_nodes and open_nodes to recall what is already known. Check memory before answering questions about people, projects, or preferences you may have seen before.
+- create_entities for new people, organizations, projects, events, and concepts. Give each a specific name and a general entityType such as "person" or "project".
+- add_observations for new atomic facts about an existing entity. Keep each observation to a single fact.
+- create_relations to connect entities. Use active voice relation types such as "works_at" or "manages".
+- delete_observations, delete_relations, and delete_entities to correct information that is wrong or outdated.
+
+While talking with the user, note new facts worth remembering and store them before the conversation ends.`,
+		},
+		{
+			Name:        "recall",
+			Description: "Recall what is known about an entity",
+			Arguments: []models.PromptArgument{
+				{Name: "name", Description: "Name of the entity to recall", Required: true},
+			},
+			Template: `Recall what you know about {{.name}}.
+
+This is the memory stored for {{.name}}:
+
+{{openNodes .name}}
+
+Summarize these facts and use them in the rest of the conversation. If the memory is empty, say that nothing is known about {{.name}} yet.`,
+		},
+		{
+			Name:        "consolidate",
+			Description: "Summarize and consolidate the memory for one entity type",
+			Arguments: []models.PromptArgument{
+				{Name: "entityType", Description: "Entity type to consolidate, such as person or project", Required: true},
+			},
+			Template: `Summarize and consolidate the memory for entities of type {{.entityType}}.
+
+These are the stored entities and the relations among them:
+
+{{entitiesOfType .entityType}}
+
+Look for duplicate entities, redundant or contradictory observations, and missing relations. Propose the add_observations, delete_observations, create_relations, and delete_entities calls that would leave the memory accurate and concise, then make them once confirmed.`,
+		},
+	}
+}
@@ -0,0 +1,170 @@
+// Package schema derives JSON Schemas for tool inputs and outputs from Go
+// types, so that the schemas advertised by tools/list cannot drift from the
+// structs the arguments are decoded into.
+//
+// Properties are named by the json struct tag. A field is required unless
+// its json tag has omitempty. Two further tags refine the schema:
+//
+//	description:"..."            sets the property description
+//	jsonschema:"minimum=1,..."   adds validation keywords; "-" omits the field
+//
+// Validation keywords are comma separated. A keyword prefixed with "items."
+// applies to the elements of an array, and enum values are separated by
+// "|", as in jsonschema:"maxItems=100,items.maxLength=256" and
+// jsonschema:"enum=in|out|both".
+//
+// For describes results and is permissive: it leaves out the validation
+// keywords and allows unknown properties. ForInput describes arguments,
+// which Validate then checks strictly.
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// For returns the JSON Schema of the type of v, which is normally the zero
+// value of a struct.
+func For(v interface{}) map[string]interface{} {
+	return of(reflect.TypeOf(v), false)
+}
+
+// ForInput returns the JSON Schema of the type of v with its validation
+// keywords, rejecting properties the type does not have.
+func ForInput(v interface{}) map[string]interface{} {
+	return of(reflect.TypeOf(v), true)
+}
+
+func of(t reflect.Type, input bool) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return of(t.Elem(), input)
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": of(t.Elem(), input)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": of(t.Elem(), input)}
+	case reflect.Struct:
+		return objectOf(t, input)
+	default:
+		// interface{} and the like accept any value
+		return map[string]interface{}{}
+	}
+}
+
+func objectOf(t reflect.Type, input bool) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	addFields(t, input, properties, &required)
+
+	s := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	if input {
+		s["additionalProperties"] = false
+	}
+	return s
+}
+
+func addFields(t reflect.Type, input bool, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Tag.Get("jsonschema") == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		// Embedded structs contribute their fields, as in encoding/json
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addFields(field.Type, input, properties, required)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		property := of(field.Type, input)
+		if description := field.Tag.Get("description"); description != "" {
+			property["description"] = description
+		}
+		if input {
+			for _, keyword := range keywords(field.Tag.Get("jsonschema")) {
+				target := property
+				if name, ok := strings.CutPrefix(keyword.name, "items."); ok {
+					target = property["items"].(map[string]interface{})
+					keyword.name = name
+				}
+				target[keyword.name] = keyword.value
+			}
+		}
+
+		properties[name] = property
+		if !hasOption(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+type keyword struct {
+	name  string
+	value interface{}
+}
+
+// keywords parses the jsonschema tag. Numeric values are kept as numbers
+// and enum values become a list.
+func keywords(tag string) []keyword {
+	if tag == "" {
+		return nil
+	}
+
+	var parsed []keyword
+	for _, part := range strings.Split(tag, ",") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			panic(fmt.Sprintf("schema: malformed jsonschema tag %q", tag))
+		}
+		if name == "enum" || strings.HasSuffix(name, ".enum") {
+			parsed = append(parsed, keyword{name, strings.Split(value, "|")})
+		} else if n, err := strconv.Atoi(value); err == nil {
+			parsed = append(parsed, keyword{name, n})
+		} else {
+			parsed = append(parsed, keyword{name, value})
+		}
+	}
+	return parsed
+}
+
+func hasOption(opts, option string) bool {
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
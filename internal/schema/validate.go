@@ -0,0 +1,183 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ValidationError reports the first value in a document that does not match
+// its schema. Path locates the value, as in entities[3].entityType.
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return "arguments " + e.Message
+	}
+	return e.Path + " " + e.Message
+}
+
+// Validate checks a decoded JSON document against a schema. It supports the
+// keywords For and ForInput generate: type, properties, required,
+// additionalProperties, items, enum, minLength, maxLength, minItems,
+// maxItems, minimum, and maximum. Other keywords are ignored.
+func Validate(s map[string]interface{}, value interface{}) error {
+	return validate(s, value, "")
+}
+
+func validate(s map[string]interface{}, value interface{}, path string) error {
+	fail := func(format string, args ...interface{}) error {
+		return &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if t, ok := s["type"].(string); ok && !hasType(value, t) {
+		return fail("must be %s", article(t))
+	}
+
+	if enum, ok := s["enum"].([]string); ok {
+		str, _ := value.(string)
+		found := false
+		for _, allowed := range enum {
+			if str == allowed {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fail("must be one of %v", enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if min, ok := number(s["minLength"]); ok && float64(length) < min {
+			if min == 1 {
+				return fail("must not be empty")
+			}
+			return fail("must be at least %v characters long", min)
+		}
+		if max, ok := number(s["maxLength"]); ok && float64(length) > max {
+			return fail("must be at most %v characters long", max)
+		}
+
+	case float64:
+		if min, ok := number(s["minimum"]); ok && v < min {
+			return fail("must be at least %v", min)
+		}
+		if max, ok := number(s["maximum"]); ok && v > max {
+			return fail("must be at most %v", max)
+		}
+
+	case []interface{}:
+		if min, ok := number(s["minItems"]); ok && float64(len(v)) < min {
+			return fail("must have at least %v items", min)
+		}
+		if max, ok := number(s["maxItems"]); ok && float64(len(v)) > max {
+			return fail("must have at most %v items", max)
+		}
+		if items, ok := s["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validate(items, item, path+"["+strconv.Itoa(i)+"]"); err != nil {
+					return err
+				}
+			}
+		}
+
+	case map[string]interface{}:
+		properties, _ := s["properties"].(map[string]interface{})
+
+		required, _ := s["required"].([]string)
+		for _, name := range required {
+			if _, ok := v[name]; !ok {
+				return &ValidationError{Path: join(path, name), Message: "is required"}
+			}
+		}
+
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			property, known := properties[name].(map[string]interface{})
+			if !known {
+				switch additional := s["additionalProperties"].(type) {
+				case bool:
+					if !additional {
+						return &ValidationError{Path: join(path, name), Message: "is not a known field"}
+					}
+					continue
+				case map[string]interface{}:
+					property = additional
+				default:
+					continue
+				}
+			}
+			if err := validate(property, v[name], join(path, name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func hasType(value interface{}, t string) bool {
+	switch t {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func article(t string) string {
+	switch t {
+	case "array", "integer", "object":
+		return "an " + t
+	case "null":
+		return "null"
+	}
+	return "a " + t
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
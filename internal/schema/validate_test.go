@@ -0,0 +1,88 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type testEntity struct {
+	Name         string   `json:"name" jsonschema:"minLength=1,maxLength=8"`
+	Kind         string   `json:"kind,omitempty" jsonschema:"enum=person|place"`
+	Observations []string `json:"observations" jsonschema:"maxItems=2,items.minLength=1"`
+}
+
+type testInput struct {
+	Entities []testEntity `json:"entities" jsonschema:"minItems=1"`
+	Depth    int          `json:"depth,omitempty" jsonschema:"minimum=1,maximum=3"`
+	Strict   bool         `json:"strict,omitempty"`
+}
+
+func TestValidate(t *testing.T) {
+	s := ForInput(testInput{})
+
+	tests := []struct {
+		name     string
+		document string
+		path     string
+		message  string
+	}{
+		{"valid", `{"entities":[{"name":"alice","kind":"person","observations":["likes tea"]}],"depth":2}`, "", ""},
+		{"not an object", `[]`, "", "must be an object"},
+		{"missing field", `{"depth":1}`, "entities", "is required"},
+		{"unknown field", `{"entities":[{"name":"a","observations":[]}],"extra":1}`, "extra", "is not a known field"},
+		{"too few items", `{"entities":[]}`, "entities", "must have at least 1 items"},
+		{"wrong type", `{"entities":[{"name":1,"observations":[]}]}`, "entities[0].name", "must be a string"},
+		{"empty string", `{"entities":[{"name":"","observations":[]}]}`, "entities[0].name", "must not be empty"},
+		{"long string", `{"entities":[{"name":"abcdefghi","observations":[]}]}`, "entities[0].name", "must be at most 8 characters long"},
+		{"enum", `{"entities":[{"name":"a","kind":"robot","observations":[]}]}`, "entities[0].kind", "must be one of [person place]"},
+		{"nested required", `{"entities":[{"name":"a"},{"name":"b"}]}`, "entities[0].observations", "is required"},
+		{"later element", `{"entities":[{"name":"a","observations":[]},{"name":"b","observations":[""]}]}`, "entities[1].observations[0]", "must not be empty"},
+		{"too many items", `{"entities":[{"name":"a","observations":["x","y","z"]}]}`, "entities[0].observations", "must have at most 2 items"},
+		{"not an integer", `{"entities":[{"name":"a","observations":[]}],"depth":1.5}`, "depth", "must be an integer"},
+		{"below minimum", `{"entities":[{"name":"a","observations":[]}],"depth":0}`, "depth", "must be at least 1"},
+		{"above maximum", `{"entities":[{"name":"a","observations":[]}],"depth":4}`, "depth", "must be at most 3"},
+		{"boolean", `{"entities":[{"name":"a","observations":[]}],"strict":"yes"}`, "strict", "must be a boolean"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var document interface{}
+			if err := json.Unmarshal([]byte(tt.document), &document); err != nil {
+				t.Fatal(err)
+			}
+
+			err := Validate(s, document)
+			if tt.message == "" {
+				if err != nil {
+					t.Fatalf("Validate = %v, want no error", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate = %v, want a ValidationError", err)
+			}
+			if validationErr.Path != tt.path || validationErr.Message != tt.message {
+				t.Errorf("Validate = %q %q, want %q %q", validationErr.Path, validationErr.Message, tt.path, tt.message)
+			}
+		})
+	}
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  ValidationError
+		want string
+	}{
+		{ValidationError{Path: "entities[3].entityType", Message: "must not be empty"}, "entities[3].entityType must not be empty"},
+		{ValidationError{Message: "must be an object"}, "arguments must be an object"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}